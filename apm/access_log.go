@@ -0,0 +1,127 @@
+package apm
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap/zapcore"
+)
+
+type accessLogConfig struct {
+	skipPaths         []string
+	successSampleRate float64
+}
+
+type AccessLogOption func(*accessLogConfig)
+
+// WithAccessLogSkipPaths disables the access log for requests to the given paths, like health checks.
+func WithAccessLogSkipPaths(paths ...string) AccessLogOption {
+	return func(cfg *accessLogConfig) {
+		cfg.skipPaths = append(cfg.skipPaths, paths...)
+	}
+}
+
+// WithAccessLogSampleRate sets the fraction between 0 and 1 of the 2xx responses that are logged.
+// Other responses are always logged. When not set, all 2xx responses are logged.
+func WithAccessLogSampleRate(rate float64) AccessLogOption {
+	return func(cfg *accessLogConfig) {
+		cfg.successSampleRate = rate
+	}
+}
+
+// WithAccessLog makes ConfigureOnRouter, WrapHandler and NewServeMux add the access log middleware after the
+// tracing middleware, see AccessLogMiddleware.
+// Example:
+//
+//	apm := apm.NewApm(apm.WithAccessLog(apm.WithAccessLogSkipPaths("/health"), apm.WithAccessLogSampleRate(0.1)))
+func WithAccessLog(opts ...AccessLogOption) ApmOption {
+	return func(apm *Apm) {
+		apm.accessLog = newAccessLogConfig(opts)
+	}
+}
+
+func newAccessLogConfig(opts []AccessLogOption) *accessLogConfig {
+	cfg := &accessLogConfig{successSampleRate: 1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// AccessLogMiddleware returns a chi middleware that logs one structured line per request, with the method,
+// route pattern, status, bytes written, duration, remote IP and user agent in the Datadog standard attributes.
+// The line is correlated to the span of the request when the tracing middleware runs before it.
+// 4xx responses are logged as warning and 5xx responses as error.
+// Example:
+//
+//	router.Use(apm.AccessLogMiddleware(apm.WithAccessLogSkipPaths("/health")))
+func (apm Apm) AccessLogMiddleware(opts ...AccessLogOption) func(http.Handler) http.Handler {
+	return apm.accessLogMiddleware(newAccessLogConfig(opts))
+}
+
+func (apm Apm) accessLogMiddleware(cfg *accessLogConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(cfg.skipPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			ww := wrapResponseWriter(w, r)
+			next.ServeHTTP(ww, r)
+			duration := time.Since(start)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := zapcore.InfoLevel
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = zapcore.ErrorLevel
+			case status >= http.StatusBadRequest:
+				level = zapcore.WarnLevel
+			case status < http.StatusMultipleChoices && rand.Float64() >= cfg.successSampleRate:
+				return
+			}
+
+			apm.Logger.Logw(r.Context(), level, "HTTP request",
+				"http.method", r.Method,
+				"http.route", routePattern(r),
+				"http.url_details.path", r.URL.Path,
+				"http.status_code", status,
+				"network.bytes_written", ww.BytesWritten(),
+				"duration", duration.Nanoseconds(),
+				"network.client.ip", remoteIP(r),
+				"http.useragent", r.UserAgent(),
+			)
+		})
+	}
+}
+
+// routePattern returns the chi or http.ServeMux route pattern matched by the request, like /orders/{id}.
+func routePattern(r *http.Request) string {
+	if routeContext := chi.RouteContext(r.Context()); routeContext != nil {
+		if pattern := routeContext.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	if r.Pattern != "" {
+		return servePatternRoute(r.Pattern)
+	}
+	return ""
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,150 @@
+package apm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// newFileLogApm creates an Apm whose logger writes JSON lines to a temporary file, returned by the read function.
+func newFileLogApm(t *testing.T, opts ...ApmOption) (Apm, func() []map[string]interface{}) {
+	logFile := filepath.Join(t.TempDir(), "apm.log")
+	apm := NewApm(append([]ApmOption{WithConfig(config.Config{OutputPaths: []string{logFile}})}, opts...)...)
+
+	return apm, func() []map[string]interface{} {
+		apm.Logger.Sync()
+
+		output, err := os.ReadFile(logFile)
+		if err != nil {
+			t.Fatalf("Unexpected error while reading log file: %v", err)
+		}
+
+		var logLines []map[string]interface{}
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		for scanner.Scan() {
+			var logLine map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &logLine); err != nil {
+				t.Fatalf("Unexpected error while decoding log line '%s': %v", scanner.Text(), err)
+			}
+			logLines = append(logLines, logLine)
+		}
+		return logLines
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		opts           []AccessLogOption
+		expectedLogged bool
+		expectedLevel  string
+		expectedStatus float64
+		expectedRoute  string
+	}{
+		{
+			name:           "successful request",
+			path:           "/orders/1",
+			expectedLogged: true,
+			expectedLevel:  "info",
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "/orders/{id}",
+		},
+		{
+			name:           "client error",
+			path:           "/orders/missing",
+			expectedLogged: true,
+			expectedLevel:  "warn",
+			expectedStatus: http.StatusNotFound,
+			expectedRoute:  "/orders/{id}",
+		},
+		{
+			name:           "server error is logged when sampled out",
+			path:           "/fail",
+			opts:           []AccessLogOption{WithAccessLogSampleRate(0)},
+			expectedLogged: true,
+			expectedLevel:  "error",
+			expectedStatus: http.StatusInternalServerError,
+			expectedRoute:  "/fail",
+		},
+		{
+			name:           "successful request sampled out",
+			path:           "/orders/1",
+			opts:           []AccessLogOption{WithAccessLogSampleRate(0)},
+			expectedLogged: false,
+		},
+		{
+			name:           "skipped path",
+			path:           "/health",
+			opts:           []AccessLogOption{WithAccessLogSkipPaths("/health")},
+			expectedLogged: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			apm, readLogLines := newFileLogApm(t, WithAccessLog(tt.opts...))
+			router := chi.NewRouter()
+			apm.ConfigureOnRouter(router)
+			router.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+				if chi.URLParam(r, "id") == "missing" {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte("order"))
+			})
+			router.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+			router.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			request.Header.Set("User-Agent", "apm-test")
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			logLines := readLogLines()
+			if !tt.expectedLogged {
+				if len(logLines) != 0 {
+					t.Errorf("expected no access log, got %v", logLines)
+				}
+				return
+			}
+			if len(logLines) != 1 {
+				t.Fatalf("expected 1 access log, got %d", len(logLines))
+			}
+
+			logLine := logLines[0]
+			expectedFields := map[string]interface{}{
+				"status":            tt.expectedLevel,
+				"http.method":       http.MethodGet,
+				"http.route":        tt.expectedRoute,
+				"http.status_code":  tt.expectedStatus,
+				"network.client.ip": "192.0.2.1",
+				"http.useragent":    "apm-test",
+			}
+			for key, expected := range expectedFields {
+				if logLine[key] != expected {
+					t.Errorf("Field %s incorrect, expected '%v' got '%v'", key, expected, logLine[key])
+				}
+			}
+			if _, ok := logLine["duration"]; !ok {
+				t.Error("expected the access log to contain the duration")
+			}
+			if _, ok := logLine["dd.trace_id"]; !ok {
+				t.Error("expected the access log to be correlated to the request span")
+			}
+		})
+	}
+}
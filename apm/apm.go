@@ -4,25 +4,69 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
-	"github.com/go-sql-driver/mysql"
-	"gorm.io/gorm"
-
+	"fmt"
 	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
 
 	sqltrace "github.com/DataDog/dd-trace-go/contrib/database/sql/v2"
 	chitrace "github.com/DataDog/dd-trace-go/contrib/go-chi/chi.v5/v2"
+	gcptrace "github.com/DataDog/dd-trace-go/contrib/google.golang.org/api/v2"
 	gormtrace "github.com/DataDog/dd-trace-go/contrib/gorm.io/gorm.v1/v2"
 	sqlxtrace "github.com/DataDog/dd-trace-go/contrib/jmoiron/sqlx/v2"
 	httptrace "github.com/DataDog/dd-trace-go/contrib/net/http/v2"
-	gcptrace "github.com/DataDog/dd-trace-go/contrib/google.golang.org/api/v2"
 	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/DataDog/dd-trace-go/v2/profiler"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/internal/openspans"
 	"github.com/YourSurpriseCom/go-datadog-apm/v2/logger"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
 	"github.com/go-chi/chi/v5"
 	"github.com/jmoiron/sqlx"
+	"golang.org/x/oauth2/google"
+	"gorm.io/gorm"
 )
 
+const googleCloudScope = "https://www.googleapis.com/auth/cloud-platform"
+
 type Apm struct {
-	Logger *logger.Logger
+	Logger  *logger.Logger
+	Metrics metrics.Metrics
+
+	startTracer   bool
+	service       string
+	env           string
+	version       string
+	agentAddr     string
+	globalTags    map[string]interface{}
+	tracerOptions []tracer.StartOption
+
+	startProfiler   bool
+	profileTypes    []profiler.ProfileType
+	profilerOptions []profiler.Option
+
+	sampleRate          *float64
+	samplingRules       []tracer.Rule
+	spanSamplingRules   []tracer.Rule
+	traceRateLimit      *float64
+	samplingErrors      []error
+	enabledIntegrations []string
+	loggerOptions       []logger.LoggerOption
+
+	accessLog *accessLogConfig
+	recovery  *recoveryConfig
+
+	startMetrics     bool
+	metricsOptions   []metrics.MetricsOption
+	startSpanMetrics bool
+	spanMetrics      *spanMetrics
+
+	dbStats         bool
+	dbStatsInterval time.Duration
+
+	shutdownHooks *shutdownHooks
 }
 
 type ApmOption func(*Apm)
@@ -33,67 +77,446 @@ func WithLogger(logger logger.Logger) ApmOption {
 	}
 }
 
+// WithTracer makes NewApm start the Datadog tracer, which is stopped again by Shutdown.
+// Any extra tracer options are applied after the ones derived from the other Apm options.
+func WithTracer(opts ...tracer.StartOption) ApmOption {
+	return func(apm *Apm) {
+		apm.startTracer = true
+		apm.tracerOptions = append(apm.tracerOptions, opts...)
+	}
+}
+
+// WithService sets the service name reported by the tracer, the profiler and the default logger.
+func WithService(service string) ApmOption {
+	return func(apm *Apm) {
+		apm.service = service
+	}
+}
+
+// WithEnv sets the environment reported by the tracer, the profiler and the default logger.
+func WithEnv(env string) ApmOption {
+	return func(apm *Apm) {
+		apm.env = env
+	}
+}
+
+// WithVersion sets the service version reported by the tracer, the profiler and the default logger.
+func WithVersion(version string) ApmOption {
+	return func(apm *Apm) {
+		apm.version = version
+	}
+}
+
+// WithAgentAddr sets the host:port of the Datadog agent the tracer sends its spans to.
+func WithAgentAddr(addr string) ApmOption {
+	return func(apm *Apm) {
+		apm.agentAddr = addr
+	}
+}
+
+// WithGlobalTag adds a tag to every span created by the tracer.
+func WithGlobalTag(key string, value interface{}) ApmOption {
+	return func(apm *Apm) {
+		if apm.globalTags == nil {
+			apm.globalTags = map[string]interface{}{}
+		}
+		apm.globalTags[key] = value
+	}
+}
+
+// WithProfiler makes NewApm start the Datadog continuous profiler, which is stopped again by Shutdown.
+// The profiler uses the service, env, version, agent address and global tags of the Apm,
+// any extra profiler options are applied after these.
+func WithProfiler(opts ...profiler.Option) ApmOption {
+	return func(apm *Apm) {
+		apm.startProfiler = true
+		apm.profilerOptions = append(apm.profilerOptions, opts...)
+	}
+}
+
+// WithProfileTypes sets the profile types collected by the profiler.
+// When not set, the CPU, heap, goroutine, mutex and block profiles are collected.
+func WithProfileTypes(types ...profiler.ProfileType) ApmOption {
+	return func(apm *Apm) {
+		apm.profileTypes = types
+	}
+}
+
+// WithConfig applies the shared configuration, see config.FromEnv.
+// The logger settings are only used when no logger is passed with WithLogger.
+func WithConfig(cfg config.Config) ApmOption {
+	return func(apm *Apm) {
+		apm.service = cfg.Service
+		apm.env = cfg.Env
+		apm.version = cfg.Version
+		apm.agentAddr = cfg.AgentAddr()
+		apm.sampleRate = cfg.SampleRate
+		apm.enabledIntegrations = cfg.EnabledIntegrations
+		apm.loggerOptions = append(apm.loggerOptions, logger.WithApmConfig(cfg))
+	}
+}
+
+// WithErrorClassifier sets the classifiers that mark errors as handled or ignored when they are recorded on a span.
+// The classifiers are only used when no logger is passed with WithLogger, see logger.WithErrorClassifier.
+func WithErrorClassifier(classifiers ...logger.ErrorClassifier) ApmOption {
+	return func(apm *Apm) {
+		apm.loggerOptions = append(apm.loggerOptions, logger.WithErrorClassifier(classifiers...))
+	}
+}
+
 // NewApm creates a new Apm instance with the provided options.
+// When WithTracer or WithProfiler is passed the tracer or profiler is started as well, call Shutdown to stop them.
 // Example:
 //
 //	myLogger := &logger.Logger{}
-//	apm := NewApm(WithLogger(myLogger))
+//	apm := NewApm(WithLogger(myLogger), WithTracer(), WithService("my-service"))
+//	defer apm.Shutdown(context.Background())
 //	apm.Logger.Info(context.Background(), "Hello, world!")
 func NewApm(options ...ApmOption) Apm {
-	apm := Apm{}
+	apm := Apm{shutdownHooks: &shutdownHooks{}}
 
 	for _, option := range options {
 		option(&apm)
 	}
 
 	if apm.Logger == nil {
-		logger := logger.NewLogger()
+		logger := logger.NewLogger(append(slices.Clone(apm.loggerOptions), apm.serviceLoggerOptions()...)...)
 		apm.Logger = &logger
 	}
 
+	if apm.Metrics == nil {
+		apm.Metrics = apm.newMetrics()
+	}
+	apm.spanMetrics = apm.newSpanMetrics()
+
+	apm.logSamplingErrors()
+
+	if apm.startTracer {
+		apm.setTraceRateLimit()
+		err := tracer.Start(apm.startOptions()...)
+		if err != nil {
+			apm.Logger.Error(context.Background(), "Error starting tracer: %s", err)
+		}
+	}
+
+	if apm.startProfiler {
+		err := profiler.Start(apm.profilerStartOptions()...)
+		if err != nil {
+			apm.Logger.Error(context.Background(), "Error starting profiler: %s", err)
+		}
+	}
+
 	return apm
 }
 
+// serviceLoggerOptions makes the default logger use the service, env and version of the Apm.
+func (apm Apm) serviceLoggerOptions() []logger.LoggerOption {
+	var opts []logger.LoggerOption
+
+	if apm.service != "" {
+		opts = append(opts, logger.WithService(apm.service))
+	}
+	if apm.env != "" {
+		opts = append(opts, logger.WithEnv(apm.env))
+	}
+	if apm.version != "" {
+		opts = append(opts, logger.WithVersion(apm.version))
+	}
+
+	return opts
+}
+
+func (apm Apm) startOptions() []tracer.StartOption {
+	var opts []tracer.StartOption
+
+	if apm.service != "" {
+		opts = append(opts, tracer.WithService(apm.service))
+	}
+	if apm.env != "" {
+		opts = append(opts, tracer.WithEnv(apm.env))
+	}
+	if apm.version != "" {
+		opts = append(opts, tracer.WithServiceVersion(apm.version))
+	}
+	if apm.agentAddr != "" {
+		opts = append(opts, tracer.WithAgentAddr(apm.agentAddr))
+	}
+	opts = append(opts, apm.samplingStartOptions()...)
+	for key, value := range apm.globalTags {
+		opts = append(opts, tracer.WithGlobalTag(key, value))
+	}
+
+	return append(opts, apm.tracerOptions...)
+}
+
+func (apm Apm) profilerStartOptions() []profiler.Option {
+	profileTypes := apm.profileTypes
+	if len(profileTypes) == 0 {
+		profileTypes = []profiler.ProfileType{
+			profiler.CPUProfile,
+			profiler.HeapProfile,
+			profiler.GoroutineProfile,
+			profiler.MutexProfile,
+			profiler.BlockProfile,
+		}
+	}
+
+	opts := []profiler.Option{profiler.WithProfileTypes(profileTypes...)}
+
+	if apm.service != "" {
+		opts = append(opts, profiler.WithService(apm.service))
+	}
+	if apm.env != "" {
+		opts = append(opts, profiler.WithEnv(apm.env))
+	}
+	if apm.version != "" {
+		opts = append(opts, profiler.WithVersion(apm.version))
+	}
+	if apm.agentAddr != "" {
+		opts = append(opts, profiler.WithAgentAddr(apm.agentAddr))
+	}
+	for key, value := range apm.globalTags {
+		opts = append(opts, profiler.WithTags(fmt.Sprintf("%s:%v", key, value)))
+	}
+
+	return append(opts, apm.profilerOptions...)
+}
+
+// Shutdown stops the background reporters of the integrations and the profiler, flushes the pending spans
+// and metrics, syncs the logger and stops the tracer started by NewApm.
+// It returns the context error when this does not complete before the context is done.
+func (apm Apm) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if apm.shutdownHooks != nil {
+			apm.shutdownHooks.run()
+		}
+		apm.stopSpanMetrics()
+		if apm.startProfiler {
+			profiler.Stop()
+		}
+		if apm.startTracer {
+			tracer.Flush()
+		}
+		if apm.Metrics != nil {
+			_ = apm.Metrics.Close()
+		}
+		apm.Logger.Sync()
+		if apm.startTracer {
+			tracer.Stop()
+		}
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shutdownHooks are the functions run by Shutdown, like stopping the background reporters of the integrations.
+type shutdownHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+func (h *shutdownHooks) add(hook func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hooks = append(h.hooks, hook)
+}
+
+// run runs the hooks once, hooks added later are run by the next call.
+func (h *shutdownHooks) run() {
+	h.mu.Lock()
+	hooks := h.hooks
+	h.hooks = nil
+	h.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// onShutdown adds a function that is run by Shutdown.
+// Without NewApm there is no Shutdown to run it, so the function is never run.
+func (apm Apm) onShutdown(hook func()) {
+	if apm.shutdownHooks != nil {
+		apm.shutdownHooks.add(hook)
+	}
+}
+
+func (apm Apm) integrationEnabled(name string) bool {
+	return config.Config{EnabledIntegrations: apm.enabledIntegrations}.IntegrationEnabled(name)
+}
+
+// StartSpanFromContext starts a span that is a child of the span in the context. The returned context tracks the
+// span, so logger.FatalContext finishes it when the process exits before the span is finished.
 func (apm Apm) StartSpanFromContext(ctx context.Context, name string) (*tracer.Span, context.Context) {
-	return tracer.StartSpanFromContext(ctx, name)
+	span, spanContext := tracer.StartSpanFromContext(ctx, name)
+	return span, openspans.ContextWithSpan(spanContext, span)
 }
 
 func (apm Apm) SpanFromContext(ctx context.Context) (*tracer.Span, bool) {
 	return tracer.SpanFromContext(ctx)
 }
 
+// RecordError records the error on the span in the context, see logger.Logger.RecordError.
+// Example:
+//
+//	apm := NewApm(WithErrorClassifier(logger.IgnoreErrors(context.Canceled), logger.HandleErrors(sql.ErrNoRows)))
+//	apm.RecordError(ctx, err)
+func (apm Apm) RecordError(ctx context.Context, err error) {
+	apm.Logger.RecordError(ctx, err)
+}
+
 func (apm Apm) ConfigureOnRouter(router *chi.Mux, opts ...chitrace.Option) {
-	router.Use(chitrace.Middleware(opts...))
+	if apm.integrationEnabled(config.IntegrationChi) {
+		router.Use(chitrace.Middleware(opts...))
+		if apm.spanMetrics != nil {
+			router.Use(httpSpanMetricsMiddleware)
+		}
+	}
+
+	if apm.accessLog != nil {
+		router.Use(apm.accessLogMiddleware(apm.accessLog))
+	}
+
+	if apm.recovery != nil {
+		router.Use(apm.recoveryMiddleware(apm.recovery))
+	}
 }
 
 func (apm Apm) ConfigureOnHttpClient(client *http.Client, opts ...httptrace.RoundTripperOption) *http.Client {
+	if !apm.integrationEnabled(config.IntegrationHttp) {
+		return client
+	}
+
 	originalClient := client
 	*client = *httptrace.WrapClient(originalClient, opts...)
 	return client
 }
 
+// ConfigureGoogleCloudClient creates a traced client for the Google Cloud APIs with the cloud-platform scope.
+// When the gcp integration is disabled an untraced client with the same scope is returned.
 func (apm Apm) ConfigureGoogleCloudClient(opts ...gcptrace.Option) (*http.Client, error) {
-	var defaultScopeOption gcptrace.Option = gcptrace.WithScopes("https://www.googleapis.com/auth/cloud-platform")
+	if !apm.integrationEnabled(config.IntegrationGCP) {
+		return google.DefaultClient(context.Background(), googleCloudScope)
+	}
+
+	var defaultScopeOption gcptrace.Option = gcptrace.WithScopes(googleCloudScope)
 
 	allOptions := append([]gcptrace.Option{defaultScopeOption}, opts...)
 
 	return gcptrace.NewClient(allOptions...)
 }
 
+// ConfigureOnSQLClient opens a traced database, the driver is registered for tracing the first time its name is used.
+// The options only apply to this database, so databases sharing a driver can have their own service name.
+// Example:
+//
+//	orders, err := apm.ConfigureOnSQLClient("mysql", &mysql.MySQLDriver{}, ordersDSN, sqltrace.WithService("orders-db"))
+//	catalog, err := apm.ConfigureOnSQLClient("mysql", &mysql.MySQLDriver{}, catalogDSN, sqltrace.WithService("catalog-db"))
 func (apm Apm) ConfigureOnSQLClient(driverName string, driver driver.Driver, dataSourceName string, opts ...sqltrace.Option) (*sql.DB, error) {
-	sqltrace.Register(driverName, driver, opts...)
+	if !apm.integrationEnabled(config.IntegrationSQL) {
+		return sql.OpenDB(dsnConnector{driver: driver, dataSourceName: dataSourceName}), nil
+	}
 
-	return sqltrace.Open(driverName, dataSourceName)
+	if err := sqlDrivers.register(driverName, driver); err != nil {
+		return nil, err
+	}
+
+	db, err := sqltrace.Open(driverName, dataSourceName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	apm.startConfiguredDBStats(db, driverName)
+
+	return db, nil
 }
 
+// ConfigureOnSQLXClient opens a traced sqlx database, like ConfigureOnSQLClient.
 func (apm Apm) ConfigureOnSQLXClient(driverName string, driver driver.Driver, dataSourceName string, opts ...sqltrace.Option) (*sqlx.DB, error) {
-	sqltrace.Register(driverName, driver, opts...)
+	if !apm.integrationEnabled(config.IntegrationSQLX) {
+		return sqlx.NewDb(sql.OpenDB(dsnConnector{driver: driver, dataSourceName: dataSourceName}), driverName), nil
+	}
 
-	return sqlxtrace.Open(driverName, dataSourceName)
+	if err := sqlDrivers.register(driverName, driver); err != nil {
+		return nil, err
+	}
+
+	db, err := sqlxtrace.Open(driverName, dataSourceName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	apm.startConfiguredDBStats(db.DB, driverName)
+
+	return db, nil
 }
 
-func (apm Apm) ConfigureOnGormMySQLClient(dialector gorm.Dialector, cfg *gorm.Config, opts ...gormtrace.Option) (*gorm.DB, error) {
-	sqltrace.Register("mysql", &mysql.MySQLDriver{})
+// ConfigureOnGormClient opens a traced gorm connection for any dialect, the gorm integration of the tracer traces
+// the operations of gorm itself, so no database/sql driver has to be registered for tracing.
+// Example:
+//
+//	db, err := apm.ConfigureOnGormClient(postgres.Open(dsn), &gorm.Config{})
+func (apm Apm) ConfigureOnGormClient(dialector gorm.Dialector, cfg *gorm.Config, opts ...gormtrace.Option) (*gorm.DB, error) {
+	if !apm.integrationEnabled(config.IntegrationGorm) {
+		return gorm.Open(dialector, cfg)
+	}
 
 	return gormtrace.Open(dialector, cfg, opts...)
 }
+
+// ConfigureOnGormMySQLClient opens a traced gorm connection for the mysql dialect, see ConfigureOnGormClient.
+func (apm Apm) ConfigureOnGormMySQLClient(dialector gorm.Dialector, cfg *gorm.Config, opts ...gormtrace.Option) (*gorm.DB, error) {
+	return apm.ConfigureOnGormClient(dialector, cfg, opts...)
+}
+
+// sqlDrivers are the drivers registered with sqltrace by the apm.
+var sqlDrivers = sqlDriverRegistry{drivers: map[string]reflect.Type{}}
+
+// sqlDriverRegistry registers every driver name with sqltrace once, sqltrace keeps the options of the first
+// registration, so the options of a connection are passed when it is opened instead.
+type sqlDriverRegistry struct {
+	mu      sync.Mutex
+	drivers map[string]reflect.Type
+}
+
+// register registers the driver with sqltrace, unless the driver name is already registered.
+// It returns an error when the driver name is already registered with a different type of driver.
+func (r *sqlDriverRegistry) register(driverName string, driver driver.Driver) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	driverType := reflect.TypeOf(driver)
+	if registered, ok := r.drivers[driverName]; ok {
+		if registered != driverType {
+			return fmt.Errorf("sql driver '%s' is already registered with driver type %s, got %s", driverName, registered, driverType)
+		}
+		return nil
+	}
+
+	sqltrace.Register(driverName, driver)
+	r.drivers[driverName] = driverType
+
+	return nil
+}
+
+// dsnConnector opens untraced connections for integrations that are disabled.
+type dsnConnector struct {
+	driver         driver.Driver
+	dataSourceName string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dataSourceName)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
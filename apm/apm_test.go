@@ -1,19 +1,37 @@
 package apm
 
 import (
+	"bytes"
 	"context"
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	sqltrace "github.com/DataDog/dd-trace-go/contrib/database/sql/v2"
 	chitrace "github.com/DataDog/dd-trace-go/contrib/go-chi/chi.v5/v2"
+	gormtrace "github.com/DataDog/dd-trace-go/contrib/gorm.io/gorm.v1/v2"
 	httptrace "github.com/DataDog/dd-trace-go/contrib/net/http/v2"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
 	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/profiler"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/internal/openspans"
 	"github.com/YourSurpriseCom/go-datadog-apm/v2/logger"
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/oauth2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestNewApm(t *testing.T) {
@@ -32,6 +50,221 @@ func TestNewApm(t *testing.T) {
 	}
 }
 
+func TestNewApmServiceLogger(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "apm.log")
+
+	apm := NewApm(
+		WithConfig(config.Config{Service: "config-service", OutputPaths: []string{logFile}}),
+		WithService("apm-test-service"),
+		WithEnv("test"),
+		WithVersion("1.2.3"),
+	)
+	apm.Logger.Info(context.Background(), "service message")
+	apm.Logger.Sync()
+
+	output, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Unexpected error while reading log file: %v", err)
+	}
+
+	var logLine map[string]interface{}
+	if err := json.Unmarshal(output, &logLine); err != nil {
+		t.Fatalf("Unexpected error while decoding log line '%s': %v", output, err)
+	}
+
+	expectedFields := map[string]string{"dd.service": "apm-test-service", "dd.env": "test", "dd.version": "1.2.3"}
+	for key, expected := range expectedFields {
+		if logLine[key] != expected {
+			t.Errorf("Field %s incorrect, expected '%s' got '%v'", key, expected, logLine[key])
+		}
+	}
+}
+
+func TestNewApmWithTracer(t *testing.T) {
+	var mu sync.Mutex
+	var payloads [][]byte
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/traces") {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			payloads = append(payloads, body)
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer agent.Close()
+
+	apm := NewApm(
+		WithTracer(),
+		WithService("apm-test-service"),
+		WithEnv("test"),
+		WithVersion("1.2.3"),
+		WithAgentAddr(strings.TrimPrefix(agent.URL, "http://")),
+		WithGlobalTag("team", "apm-test-team"),
+	)
+
+	span, _ := apm.StartSpanFromContext(context.Background(), "test.tracer")
+	span.Finish()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := apm.Shutdown(ctx); err != nil {
+		t.Fatalf("Unexpected error while shutting down: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	received := bytes.Join(payloads, nil)
+	for _, expected := range []string{"test.tracer", "apm-test-service", "1.2.3", "apm-test-team"} {
+		if !bytes.Contains(received, []byte(expected)) {
+			t.Errorf("expected agent to receive '%s' in the traces payload", expected)
+		}
+	}
+}
+
+func TestNewApmWithProfiler(t *testing.T) {
+	uploads := make(chan *http.Request, 10)
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/profiling/") {
+			select {
+			case uploads <- r:
+			default:
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer agent.Close()
+
+	apm := NewApm(
+		WithProfiler(profiler.WithPeriod(10*time.Millisecond), profiler.CPUDuration(10*time.Millisecond)),
+		WithProfileTypes(profiler.HeapProfile),
+		WithService("apm-test-service"),
+		WithAgentAddr(strings.TrimPrefix(agent.URL, "http://")),
+	)
+
+	select {
+	case upload := <-uploads:
+		if upload.Method != http.MethodPost {
+			t.Errorf("expected profile upload to be a POST request, got '%s'", upload.Method)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected the profiler to upload a profile to the agent")
+	}
+
+	if err := apm.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error while shutting down: %v", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	t.Run("without tracer", func(t *testing.T) {
+		apm := NewApm()
+
+		if err := apm.Shutdown(context.Background()); err != nil {
+			t.Errorf("Unexpected error while shutting down: %v", err)
+		}
+	})
+
+	t.Run("with expired context", func(t *testing.T) {
+		apm := NewApm()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := apm.Shutdown(ctx)
+		if err != nil && err != context.Canceled {
+			t.Errorf("expected nil or context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("runs shutdown hooks once", func(t *testing.T) {
+		apm := NewApm()
+		runs := 0
+		apm.onShutdown(func() { runs++ })
+
+		for range 2 {
+			if err := apm.Shutdown(context.Background()); err != nil {
+				t.Errorf("Unexpected error while shutting down: %v", err)
+			}
+		}
+		if runs != 1 {
+			t.Errorf("Shutdown hook runs incorrect, expected '1' got '%d'", runs)
+		}
+	})
+}
+
+func TestNewApmWithConfig(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm := NewApm(WithConfig(config.Config{
+		Service:             "apm-test-service",
+		AgentHost:           "datadog-agent",
+		LogLevel:            zapcore.ErrorLevel,
+		EnabledIntegrations: []string{config.IntegrationHttp},
+	}))
+
+	if apm.service != "apm-test-service" {
+		t.Errorf("Service incorrect, expected 'apm-test-service' got '%s'", apm.service)
+	}
+	if apm.agentAddr != "datadog-agent:8126" {
+		t.Errorf("Agent address incorrect, expected 'datadog-agent:8126' got '%s'", apm.agentAddr)
+	}
+
+	t.Run("disabled router integration", func(t *testing.T) {
+		router := chi.NewRouter()
+		apm.ConfigureOnRouter(router)
+
+		if len(router.Middlewares()) != 0 {
+			t.Errorf("expected no middleware to be added, got %d", len(router.Middlewares()))
+		}
+	})
+
+	t.Run("disabled sql integration", func(t *testing.T) {
+		db, err := apm.ConfigureOnSQLClient("mock-untraced-sql-driver", &mockDriver{}, "mock-connection-string")
+		if err != nil {
+			t.Fatalf("Failed to configure SQL client: %v", err)
+		}
+
+		if err := db.Ping(); err != nil {
+			t.Fatalf("Unexpected error while running db.Ping: %v", err)
+		}
+		if spans := mt.FinishedSpans(); len(spans) != 0 {
+			t.Errorf("expected 0 spans, got %d", len(spans))
+		}
+	})
+
+	t.Run("disabled sqlx integration", func(t *testing.T) {
+		db, err := apm.ConfigureOnSQLXClient("mock-untraced-sqlx-driver", &mockDriver{}, "mock-connection-string")
+		if err != nil {
+			t.Fatalf("Failed to configure SQLX client: %v", err)
+		}
+
+		if err := db.Ping(); err != nil {
+			t.Fatalf("Unexpected error while running db.Ping: %v", err)
+		}
+		if spans := mt.FinishedSpans(); len(spans) != 0 {
+			t.Errorf("expected 0 spans, got %d", len(spans))
+		}
+	})
+
+	t.Run("disabled gcp integration", func(t *testing.T) {
+		credentials := filepath.Join(t.TempDir(), "credentials.json")
+		if err := os.WriteFile(credentials, []byte(`{"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "token"}`), 0o600); err != nil {
+			t.Fatalf("Failed to write the credentials: %v", err)
+		}
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentials)
+
+		client, err := apm.ConfigureGoogleCloudClient()
+		if err != nil {
+			t.Fatalf("Failed to configure Google Cloud client: %v", err)
+		}
+
+		if _, ok := client.Transport.(*oauth2.Transport); !ok {
+			t.Errorf("Transport incorrect, expected an untraced '*oauth2.Transport' got '%T'", client.Transport)
+		}
+	})
+}
+
 func TestStartSpanFromContext(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
@@ -44,6 +277,9 @@ func TestStartSpanFromContext(t *testing.T) {
 	if spanContext == ctx {
 		t.Fatal("expected 'spanContext' not to be the same as 'ctx'")
 	}
+	if openSpans := openspans.FromContext(spanContext); len(openSpans) != 1 || openSpans[0] != span {
+		t.Errorf("expected 'spanContext' to track the span, got '%v'", openSpans)
+	}
 
 	spans := mt.FinishedSpans()
 	if len(spans) != 1 {
@@ -83,6 +319,32 @@ func TestSpanFromContext(t *testing.T) {
 	})
 }
 
+func TestRecordError(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm := NewApm(WithErrorClassifier(logger.IgnoreErrors(context.Canceled)))
+
+	span, spanContext := apm.StartSpanFromContext(context.Background(), "test span")
+	apm.RecordError(spanContext, context.Canceled)
+	span.Finish()
+
+	span, spanContext = apm.StartSpanFromContext(context.Background(), "test span")
+	apm.RecordError(spanContext, errors.New("request failed"))
+	span.Finish()
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Tag(ext.ErrorMsg) != nil {
+		t.Errorf("expected ignored error not to be recorded, got '%v'", spans[0].Tag(ext.ErrorMsg))
+	}
+	if spans[1].Tag(ext.ErrorMsg) != "request failed" {
+		t.Errorf("Error message incorrect, expected 'request failed' got '%v'", spans[1].Tag(ext.ErrorMsg))
+	}
+}
+
 func TestConfigureOnRouter(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
@@ -228,6 +490,60 @@ func TestConfigureOnSQLXClient(t *testing.T) {
 
 }
 
+func TestConfigureOnSQLClientServicePerDatabase(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm := NewApm()
+	for _, service := range []string{"orders-db", "catalog-db"} {
+		db, err := apm.ConfigureOnSQLClient("mock-shared-driver", &mockDriver{}, "mock-connection-string", sqltrace.WithService(service))
+		if err != nil {
+			t.Fatalf("Failed to configure SQL client for %s: %v", service, err)
+		}
+		if err := db.Ping(); err != nil {
+			t.Fatalf("Unexpected error while running db.Ping: %v", err)
+		}
+	}
+
+	var services []interface{}
+	for _, span := range mt.FinishedSpans() {
+		if span.Tag("sql.query_type") == "Ping" {
+			services = append(services, span.Tag(ext.ServiceName))
+		}
+	}
+	if !reflect.DeepEqual(services, []interface{}{"orders-db", "catalog-db"}) {
+		t.Errorf("Ping span services incorrect, expected '[orders-db catalog-db]' got '%v'", services)
+	}
+}
+
+// otherMockDriver is a driver of another type than mockDriver.
+type otherMockDriver struct {
+	mockDriver
+}
+
+func TestSQLDriverRegistry(t *testing.T) {
+	registry := sqlDriverRegistry{drivers: map[string]reflect.Type{}}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := registry.register("mock-registry-driver", &mockDriver{}); err != nil {
+				t.Errorf("Unexpected error while registering the driver: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(registry.drivers) != 1 {
+		t.Errorf("Registered drivers incorrect, expected '1' got '%d'", len(registry.drivers))
+	}
+	if err := registry.register("mock-registry-driver", &otherMockDriver{}); err == nil {
+		t.Error("expected an error while registering another driver type under the same name")
+	}
+}
+
 // mockDriver implements database/sql/driver.Driver interface
 type mockDriver struct{}
 
@@ -305,3 +621,50 @@ func (r *mockRows) Close() error {
 func (r *mockRows) Next(dest []driver.Value) error {
 	return nil
 }
+
+func TestConfigureOnGormClientSQLite(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	type order struct {
+		ID     uint
+		Status string
+	}
+
+	apm := NewApm()
+	db, err := apm.ConfigureOnGormClient(sqlite.Open(filepath.Join(t.TempDir(), "orders.db")), &gorm.Config{}, gormtrace.WithService("orders-db"))
+	if err != nil {
+		t.Fatalf("Failed to configure gorm client: %v", err)
+	}
+	if err := db.AutoMigrate(&order{}); err != nil {
+		t.Fatalf("Unexpected error while migrating: %v", err)
+	}
+	mt.Reset()
+
+	if err := db.Create(&order{Status: "paid"}).Error; err != nil {
+		t.Fatalf("Unexpected error while creating: %v", err)
+	}
+	var orders []order
+	if err := db.Where("status = ?", "paid").Find(&orders).Error; err != nil {
+		t.Fatalf("Unexpected error while querying: %v", err)
+	}
+	if len(orders) != 1 {
+		t.Errorf("Orders incorrect, expected '1' got '%d'", len(orders))
+	}
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	for i, expected := range []string{"gorm.create", "gorm.query"} {
+		if spans[i].OperationName() != expected {
+			t.Errorf("Operation name incorrect, expected '%s' got '%s'", expected, spans[i].OperationName())
+		}
+		if spans[i].Tag(ext.ServiceName) != "orders-db" {
+			t.Errorf("Service name incorrect, expected 'orders-db' got '%v'", spans[i].Tag(ext.ServiceName))
+		}
+	}
+	if resource := spans[1].Tag(ext.ResourceName); resource != "SELECT * FROM `orders` WHERE status = ?" {
+		t.Errorf("Resource name incorrect, expected 'SELECT * FROM `orders` WHERE status = ?' got '%v'", resource)
+	}
+}
@@ -0,0 +1,72 @@
+package apm
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
+)
+
+const (
+	dbStatsMetricPrefix    = "sql.db."
+	defaultDBStatsInterval = 10 * time.Second
+)
+
+// WithDBStats makes the databases opened by ConfigureOnSQLClient and ConfigureOnSQLXClient report the statistics
+// of their connection pool as gauges to the Metrics of the Apm, every interval until Shutdown is called.
+// The statistics are tagged with the driver name, use ReportDBStats instead to tag a database with its service.
+// Without WithMetrics the statistics are sent with their own DogStatsD client, to the agent host of the Apm.
+// The interval is also used by ReportDBStats, when 0 the statistics are reported every 10 seconds.
+func WithDBStats(interval time.Duration) ApmOption {
+	return func(apm *Apm) {
+		apm.dbStats = true
+		apm.dbStatsInterval = interval
+	}
+}
+
+// ReportDBStats reports the statistics of the connection pool of the database to the Metrics of the Apm, or its own
+// DogStatsD client without WithMetrics, as gauges prefixed with "sql.db." and tagged with the service of the database,
+// until Shutdown is called.
+// It is meant for databases that are not opened by the apm, or opened by the apm without WithDBStats.
+// Example:
+//
+//	db, err := apm.ConfigureOnSQLClient("mysql", &mysql.MySQLDriver{}, dsn, sqltrace.WithService("orders-db"))
+//	apm.ReportDBStats(db, "orders-db")
+func (apm Apm) ReportDBStats(db *sql.DB, service string) {
+	apm.startDBStats(db, []string{"db.service:" + service})
+}
+
+// startConfiguredDBStats starts the statistics of a database opened by the apm, when enabled with WithDBStats.
+func (apm Apm) startConfiguredDBStats(db *sql.DB, driverName string) {
+	if apm.dbStats {
+		apm.startDBStats(db, []string{"db.driver:" + driverName})
+	}
+}
+
+func (apm Apm) startDBStats(db *sql.DB, tags []string) {
+	interval := apm.dbStatsInterval
+	if interval <= 0 {
+		interval = defaultDBStatsInterval
+	}
+
+	apm.startStatsReporter(interval, func(client metrics.Metrics) {
+		reportDBStats(client, db.Stats(), tags)
+	})
+}
+
+func reportDBStats(client metrics.Metrics, stats sql.DBStats, tags []string) {
+	gauges := map[string]float64{
+		"max_open_connections": float64(stats.MaxOpenConnections),
+		"open_connections":     float64(stats.OpenConnections),
+		"in_use":               float64(stats.InUse),
+		"idle":                 float64(stats.Idle),
+		"wait_count":           float64(stats.WaitCount),
+		"wait_duration":        stats.WaitDuration.Seconds(),
+		"max_idle_closed":      float64(stats.MaxIdleClosed),
+		"max_idle_time_closed": float64(stats.MaxIdleTimeClosed),
+		"max_lifetime_closed":  float64(stats.MaxLifetimeClosed),
+	}
+	for name, value := range gauges {
+		client.Gauge(dbStatsMetricPrefix+name, value, tags...)
+	}
+}
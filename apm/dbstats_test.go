@@ -0,0 +1,137 @@
+package apm
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	sqltrace "github.com/DataDog/dd-trace-go/contrib/database/sql/v2"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
+)
+
+// assertGauges checks the last value and the tags of the gauges recorded by the recorder.
+func assertGauges(t *testing.T, recorder *metrics.Recorder, expected map[string]float64, expectedTag string) {
+	t.Helper()
+
+	for name, value := range expected {
+		metric, ok := recorder.Last(name)
+		if !ok {
+			t.Errorf("expected the %s gauge, got none", name)
+			continue
+		}
+		if metric.Type != metrics.TypeGauge || metric.Value != value {
+			t.Errorf("Gauge %s incorrect, expected '%v' got %s '%v'", name, value, metric.Type, metric.Value)
+		}
+		if !slices.Contains(metric.Tags, expectedTag) {
+			t.Errorf("Gauge %s tags incorrect, expected '%s' in '%v'", name, expectedTag, metric.Tags)
+		}
+	}
+}
+
+func TestReportDBStatsGauges(t *testing.T) {
+	db := sql.OpenDB(dsnConnector{driver: &mockDriver{}, dataSourceName: "mock-connection-string"})
+	defer db.Close()
+	db.SetMaxOpenConns(5)
+	if err := db.Ping(); err != nil {
+		t.Fatalf("Unexpected error while running db.Ping: %v", err)
+	}
+
+	recorder := metrics.NewRecorder()
+	reportDBStats(recorder, db.Stats(), []string{"db.service:orders-db"})
+
+	expected := map[string]float64{
+		"sql.db.max_open_connections": 5,
+		"sql.db.open_connections":     1,
+		"sql.db.in_use":               0,
+		"sql.db.idle":                 1,
+		"sql.db.wait_count":           0,
+	}
+	assertGauges(t, recorder, expected, "db.service:orders-db")
+}
+
+func TestReportDBStats(t *testing.T) {
+	listener, err := metrics.ListenUDP()
+	if err != nil {
+		t.Fatalf("Unexpected error while listening: %v", err)
+	}
+	defer listener.Close()
+
+	db := sql.OpenDB(dsnConnector{driver: &mockDriver{}, dataSourceName: "mock-connection-string"})
+	defer db.Close()
+
+	apm := NewApm(
+		WithService("apm-test-service"),
+		WithMetrics(metrics.WithAddr(listener.Addr())),
+		WithDBStats(10*time.Millisecond),
+	)
+	apm.ReportDBStats(db, "orders-db")
+
+	// the metrics are flushed when they are closed by Shutdown
+	time.Sleep(50 * time.Millisecond)
+	if err := apm.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error while shutting down: %v", err)
+	}
+
+	openConnections, err := listener.NextWithPrefix("sql.db.open_connections:", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error while reading the metrics: %v", err)
+	}
+	for _, tag := range []string{"db.service:orders-db", "service:apm-test-service"} {
+		if !strings.Contains(openConnections, tag) {
+			t.Errorf("Gauge tags incorrect, expected '%s' in '%s'", tag, openConnections)
+		}
+	}
+}
+
+func TestConfigureOnSQLClientDBStats(t *testing.T) {
+	recorder := metrics.NewRecorder()
+	apm := NewApm(WithMetricsClient(recorder), WithDBStats(10*time.Millisecond))
+	db, err := apm.ConfigureOnSQLClient("mock-stats-driver", &mockDriver{}, "mock-connection-string", sqltrace.WithService("orders-db"))
+	if err != nil {
+		t.Fatalf("Failed to configure SQL client: %v", err)
+	}
+	defer db.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := apm.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error while shutting down: %v", err)
+	}
+
+	assertGauges(t, recorder, map[string]float64{"sql.db.idle": 0}, "db.driver:mock-stats-driver")
+}
+
+func TestDBStatsWithoutMetrics(t *testing.T) {
+	listener, err := metrics.ListenUDP()
+	if err != nil {
+		t.Fatalf("Unexpected error while listening: %v", err)
+	}
+	defer listener.Close()
+	t.Setenv("DD_DOGSTATSD_URL", "udp://"+listener.Addr())
+
+	db := sql.OpenDB(dsnConnector{driver: &mockDriver{}, dataSourceName: "mock-connection-string"})
+	defer db.Close()
+
+	apm := NewApm(WithDBStats(10 * time.Millisecond))
+	apm.ReportDBStats(db, "orders-db")
+
+	if _, ok := apm.Metrics.(metrics.NoopMetrics); !ok {
+		t.Errorf("Metrics incorrect, expected metrics.NoopMetrics got %T", apm.Metrics)
+	}
+
+	// the statistics are flushed when the reporter closes its client on Shutdown
+	time.Sleep(50 * time.Millisecond)
+	if err := apm.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error while shutting down: %v", err)
+	}
+
+	openConnections, err := listener.NextWithPrefix("sql.db.open_connections:", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error while reading the metrics: %v", err)
+	}
+	if !strings.Contains(openConnections, "db.service:orders-db") {
+		t.Errorf("Gauge tags incorrect, expected 'db.service:orders-db' in '%s'", openConnections)
+	}
+}
@@ -0,0 +1,172 @@
+package apm
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	grpctrace "github.com/DataDog/dd-trace-go/contrib/google.golang.org/grpc/v2"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	grpcStatusCodeTag  = "rpc.grpc.status_code"
+	grpcServerSpanName = "grpc.server"
+	grpcClientSpanName = "grpc.client"
+)
+
+// defaultGRPCNonErrorCodes are the status codes caused by the caller, like the 4xx HTTP status codes.
+var defaultGRPCNonErrorCodes = []codes.Code{
+	codes.Canceled,
+	codes.InvalidArgument,
+	codes.NotFound,
+	codes.AlreadyExists,
+	codes.PermissionDenied,
+	codes.FailedPrecondition,
+	codes.OutOfRange,
+	codes.Unauthenticated,
+}
+
+type grpcConfig struct {
+	trace         bool
+	nonErrorCodes []codes.Code
+}
+
+type GRPCOption func(*grpcConfig)
+
+// WithGRPCNonErrorCodes sets the status codes that do not mark the span as errored and are logged as warning.
+// When not set, the codes caused by the caller are used: Canceled, InvalidArgument, NotFound, AlreadyExists,
+// PermissionDenied, FailedPrecondition, OutOfRange and Unauthenticated.
+func WithGRPCNonErrorCodes(nonErrorCodes ...codes.Code) GRPCOption {
+	return func(cfg *grpcConfig) {
+		cfg.nonErrorCodes = nonErrorCodes
+	}
+}
+
+func (apm Apm) newGRPCConfig(opts []GRPCOption) *grpcConfig {
+	cfg := &grpcConfig{
+		trace:         apm.integrationEnabled(config.IntegrationGRPC),
+		nonErrorCodes: defaultGRPCNonErrorCodes,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// traceOptions are the options of the gRPC integration of the tracer, which creates one span per call
+// and classifies the status codes like the logs of the apm.
+func (cfg *grpcConfig) traceOptions(service string) []grpctrace.Option {
+	opts := []grpctrace.Option{
+		grpctrace.NonErrorCodes(cfg.nonErrorCodes...),
+		grpctrace.WithStreamMessages(false),
+	}
+	if service != "" {
+		opts = append(opts, grpctrace.WithService(service))
+	}
+	return opts
+}
+
+// isError reports whether the error marks the span as errored.
+func (cfg *grpcConfig) isError(err error) bool {
+	return err != nil && !slices.Contains(cfg.nonErrorCodes, status.Code(err))
+}
+
+// GRPCServerOptions returns the server options that trace every call with the gRPC integration of the tracer,
+// continuing the trace of the client. Errors are logged with the trace correlation of the call and classified
+// by status code, and panics of the handlers are recovered, logged and returned as an Internal error.
+// Example:
+//
+//	server := grpc.NewServer(apm.GRPCServerOptions()...)
+func (apm Apm) GRPCServerOptions(opts ...GRPCOption) []grpc.ServerOption {
+	cfg := apm.newGRPCConfig(opts)
+	if !cfg.trace {
+		return []grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(apm.unaryServerInterceptor(cfg)),
+			grpc.ChainStreamInterceptor(apm.streamServerInterceptor(cfg)),
+		}
+	}
+
+	// chained after the tracing interceptors, so the calls are logged inside their span
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpctrace.UnaryServerInterceptor(cfg.traceOptions(apm.service)...), apm.unaryServerInterceptor(cfg)),
+		grpc.ChainStreamInterceptor(grpctrace.StreamServerInterceptor(cfg.traceOptions(apm.service)...), apm.streamServerInterceptor(cfg)),
+	}
+}
+
+// GRPCDialOptions returns the dial options that trace every call with the gRPC integration of the tracer
+// and propagate the trace to the server.
+// Example:
+//
+//	conn, err := grpc.NewClient(target, append(apm.GRPCDialOptions(), grpc.WithTransportCredentials(creds))...)
+func (apm Apm) GRPCDialOptions(opts ...GRPCOption) []grpc.DialOption {
+	cfg := apm.newGRPCConfig(opts)
+	if !cfg.trace {
+		return nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(grpctrace.UnaryClientInterceptor(cfg.traceOptions("")...)),
+		grpc.WithChainStreamInterceptor(grpctrace.StreamClientInterceptor(cfg.traceOptions("")...)),
+	}
+}
+
+func (apm Apm) unaryServerInterceptor(cfg *grpcConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				apm.Logger.ErrorErr(ctx, panicError(recovered), "Recovered from panic", ext.GRPCFullMethod, info.FullMethod)
+				err = status.Error(codes.Internal, codes.Internal.String())
+			} else {
+				apm.logGRPCError(ctx, cfg, info.FullMethod, err)
+			}
+			apm.observeGRPCCall(cfg, info.FullMethod, start, err)
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func (apm Apm) streamServerInterceptor(cfg *grpcConfig) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				apm.Logger.ErrorErr(stream.Context(), panicError(recovered), "Recovered from panic", ext.GRPCFullMethod, info.FullMethod)
+				err = status.Error(codes.Internal, codes.Internal.String())
+			} else {
+				apm.logGRPCError(stream.Context(), cfg, info.FullMethod, err)
+			}
+			apm.observeGRPCCall(cfg, info.FullMethod, start, err)
+		}()
+
+		return handler(srv, stream)
+	}
+}
+
+// logGRPCError logs errors marking the span as errored at error level and records them on the span,
+// other errors are logged at warning level.
+func (apm Apm) logGRPCError(ctx context.Context, cfg *grpcConfig, method string, err error) {
+	if err == nil {
+		return
+	}
+
+	code := status.Code(err).String()
+	if !cfg.isError(err) {
+		apm.Logger.Warnw(ctx, "gRPC call failed", ext.GRPCFullMethod, method, grpcStatusCodeTag, code, "error", err)
+		return
+	}
+	apm.Logger.ErrorErr(ctx, err, "gRPC call failed", ext.GRPCFullMethod, method, grpcStatusCodeTag, code)
+}
+
+// observeGRPCCall reports the span metrics of a traced server call that started at start.
+func (apm Apm) observeGRPCCall(cfg *grpcConfig, method string, start time.Time, err error) {
+	if cfg.trace {
+		observeSpan(grpcServerSpanName, "", method, start, cfg.isError(err))
+	}
+}
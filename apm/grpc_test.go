@@ -0,0 +1,385 @@
+package apm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// grpcCodeTag is the status code tag set by the gRPC integration of the tracer.
+const grpcCodeTag = "grpc.code"
+
+// testHealthServer answers depending on the requested service name.
+type testHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (testHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	switch req.GetService() {
+	case "panic":
+		panic("boom")
+	case "missing":
+		return nil, status.Error(codes.NotFound, "service not found")
+	case "unavailable":
+		return nil, status.Error(codes.Unavailable, "database unavailable")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (testHealthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	if req.GetService() == "unavailable" {
+		return status.Error(codes.Unavailable, "database unavailable")
+	}
+	return stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+}
+
+// testStreamsServiceDesc describes a service with a client streaming and a long running server streaming method,
+// which reuse the health check messages.
+var testStreamsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Streams",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Upload",
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				for {
+					if err := stream.RecvMsg(&grpc_health_v1.HealthCheckRequest{}); err == io.EOF {
+						return stream.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+					} else if err != nil {
+						return err
+					}
+				}
+			},
+			ClientStreams: true,
+		},
+		{
+			StreamName: "Subscribe",
+			Handler: func(_ interface{}, stream grpc.ServerStream) error {
+				if err := stream.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+					return err
+				}
+				<-stream.Context().Done()
+				return stream.Context().Err()
+			},
+			ServerStreams: true,
+		},
+	},
+}
+
+// newGRPCTestClient starts an in-process server with the server options of the apm,
+// and returns a client connected to it with the dial options of the apm.
+func newGRPCTestClient(t *testing.T, apm Apm) grpc_health_v1.HealthClient {
+	return grpc_health_v1.NewHealthClient(newGRPCTestConn(t, apm))
+}
+
+// newGRPCTestConn starts an in-process server with the health and test streams services,
+// and returns a connection to it with the dial options of the apm.
+func newGRPCTestConn(t *testing.T, apm Apm) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(apm.GRPCServerOptions()...)
+	grpc_health_v1.RegisterHealthServer(server, testHealthServer{})
+	server.RegisterService(&testStreamsServiceDesc, nil)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	dialOptions := append(apm.GRPCDialOptions(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufconn", dialOptions...)
+	if err != nil {
+		t.Fatalf("Unexpected error while creating the client: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestGRPCUnary(t *testing.T) {
+	tests := []struct {
+		name                string
+		service             string
+		expectedCode        codes.Code
+		expectedError       bool
+		expectedLogStatus   string
+		expectedLogErrorMsg string
+	}{
+		{
+			name:         "successful call",
+			service:      "orders",
+			expectedCode: codes.OK,
+		},
+		{
+			name:              "non error code",
+			service:           "missing",
+			expectedCode:      codes.NotFound,
+			expectedLogStatus: "warn",
+		},
+		{
+			name:                "error code",
+			service:             "unavailable",
+			expectedCode:        codes.Unavailable,
+			expectedError:       true,
+			expectedLogStatus:   "error",
+			expectedLogErrorMsg: "rpc error: code = Unavailable desc = database unavailable",
+		},
+		{
+			name:                "panic",
+			service:             "panic",
+			expectedCode:        codes.Internal,
+			expectedError:       true,
+			expectedLogStatus:   "error",
+			expectedLogErrorMsg: "panic: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			apm, readLogLines := newFileLogApm(t)
+			client := newGRPCTestClient(t, apm)
+
+			_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: tt.service})
+			if status.Code(err) != tt.expectedCode {
+				t.Errorf("Status code incorrect, expected '%s' got '%s'", tt.expectedCode, status.Code(err))
+			}
+
+			spans := mt.FinishedSpans()
+			if len(spans) != 2 {
+				t.Fatalf("expected 2 spans, got %d", len(spans))
+			}
+			serverSpan, clientSpan := spans[0], spans[1]
+			if serverSpan.OperationName() != grpcServerSpanName || clientSpan.OperationName() != grpcClientSpanName {
+				t.Fatalf("expected a server and a client span, got '%s' and '%s'", serverSpan.OperationName(), clientSpan.OperationName())
+			}
+			if serverSpan.ParentID() != clientSpan.SpanID() {
+				t.Errorf("Server span parent incorrect, expected '%d' got '%d'", clientSpan.SpanID(), serverSpan.ParentID())
+			}
+
+			for _, span := range spans {
+				if span.Tag(ext.ResourceName) != "/grpc.health.v1.Health/Check" {
+					t.Errorf("Resource name incorrect, expected '/grpc.health.v1.Health/Check' got '%v'", span.Tag(ext.ResourceName))
+				}
+				if span.Tag(grpcCodeTag) != tt.expectedCode.String() {
+					t.Errorf("Status code tag incorrect, expected '%s' got '%v'", tt.expectedCode, span.Tag(grpcCodeTag))
+				}
+				if isErrored := span.Tag(ext.MapSpanError) == int32(1); isErrored != tt.expectedError {
+					t.Errorf("Span %s error flag incorrect, expected '%v' got '%v'", span.OperationName(), tt.expectedError, isErrored)
+				}
+			}
+
+			logLines := readLogLines()
+			if tt.expectedLogStatus == "" {
+				if len(logLines) != 0 {
+					t.Errorf("expected no logs, got %v", logLines)
+				}
+				return
+			}
+			if len(logLines) != 1 {
+				t.Fatalf("expected 1 log, got %d", len(logLines))
+			}
+			if logLines[0]["status"] != tt.expectedLogStatus {
+				t.Errorf("Log status incorrect, expected '%s' got '%v'", tt.expectedLogStatus, logLines[0]["status"])
+			}
+			if tt.expectedLogErrorMsg != "" && logLines[0]["error.message"] != tt.expectedLogErrorMsg {
+				t.Errorf("Log error.message incorrect, expected '%s' got '%v'", tt.expectedLogErrorMsg, logLines[0]["error.message"])
+			}
+			if _, ok := logLines[0]["dd.trace_id"]; !ok {
+				t.Error("expected the log to be correlated to the server span")
+			}
+		})
+	}
+}
+
+func TestGRPCStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		service       string
+		expectedCode  codes.Code
+		expectedError bool
+	}{
+		{
+			name:         "successful stream",
+			service:      "orders",
+			expectedCode: codes.OK,
+		},
+		{
+			name:          "error code",
+			service:       "unavailable",
+			expectedCode:  codes.Unavailable,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			apm, _ := newFileLogApm(t)
+			client := newGRPCTestClient(t, apm)
+
+			stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: tt.service})
+			if err != nil {
+				t.Fatalf("Unexpected error while starting the stream: %v", err)
+			}
+			for err == nil {
+				_, err = stream.Recv()
+			}
+			if !errors.Is(err, io.EOF) && status.Code(err) != tt.expectedCode {
+				t.Errorf("Status code incorrect, expected '%s' got '%s'", tt.expectedCode, status.Code(err))
+			}
+
+			// the client span is finished when the context of the stream is done, without the status of the call
+			clientSpan := waitForGRPCClientSpan(t, mt)
+			if clientSpan.Tag(ext.ResourceName) != "/grpc.health.v1.Health/Watch" {
+				t.Errorf("Resource name incorrect, expected '/grpc.health.v1.Health/Watch' got '%v'", clientSpan.Tag(ext.ResourceName))
+			}
+
+			spans := mt.FinishedSpans()
+			if len(spans) != 2 {
+				t.Fatalf("expected 2 spans, got %d", len(spans))
+			}
+			serverSpan := spans[0]
+			if serverSpan.OperationName() != grpcServerSpanName {
+				t.Fatalf("expected the server span, got '%s'", serverSpan.OperationName())
+			}
+			if serverSpan.Tag(grpcCodeTag) != tt.expectedCode.String() {
+				t.Errorf("Status code tag incorrect, expected '%s' got '%v'", tt.expectedCode, serverSpan.Tag(grpcCodeTag))
+			}
+			if isErrored := serverSpan.Tag(ext.MapSpanError) == int32(1); isErrored != tt.expectedError {
+				t.Errorf("Server span error flag incorrect, expected '%v' got '%v'", tt.expectedError, isErrored)
+			}
+		})
+	}
+}
+
+func TestGRPCClientStream(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm, _ := newFileLogApm(t)
+	conn := newGRPCTestConn(t, apm)
+
+	stream, err := conn.NewStream(context.Background(), &testStreamsServiceDesc.Streams[0], "/test.Streams/Upload")
+	if err != nil {
+		t.Fatalf("Unexpected error while starting the stream: %v", err)
+	}
+	for _, service := range []string{"orders", "invoices"} {
+		if err := stream.SendMsg(&grpc_health_v1.HealthCheckRequest{Service: service}); err != nil {
+			t.Fatalf("Unexpected error while sending: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("Unexpected error while closing: %v", err)
+	}
+
+	// like CloseAndRecv, the response is received without io.EOF
+	if err := stream.RecvMsg(&grpc_health_v1.HealthCheckResponse{}); err != nil {
+		t.Fatalf("Unexpected error while receiving: %v", err)
+	}
+
+	clientSpan := waitForGRPCClientSpan(t, mt)
+	if clientSpan.Tag(ext.ResourceName) != "/test.Streams/Upload" {
+		t.Errorf("Resource name incorrect, expected '/test.Streams/Upload' got '%v'", clientSpan.Tag(ext.ResourceName))
+	}
+	if clientSpan.Tag(grpcCodeTag) != codes.OK.String() {
+		t.Errorf("Status code tag incorrect, expected '%s' got '%v'", codes.OK, clientSpan.Tag(grpcCodeTag))
+	}
+}
+
+func TestGRPCCanceledStream(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm, _ := newFileLogApm(t)
+	conn := newGRPCTestConn(t, apm)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := conn.NewStream(ctx, &testStreamsServiceDesc.Streams[1], "/test.Streams/Subscribe")
+	if err != nil {
+		t.Fatalf("Unexpected error while starting the stream: %v", err)
+	}
+	if err := stream.SendMsg(&grpc_health_v1.HealthCheckRequest{Service: "orders"}); err != nil {
+		t.Fatalf("Unexpected error while sending: %v", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("Unexpected error while closing: %v", err)
+	}
+	if err := stream.RecvMsg(&grpc_health_v1.HealthCheckResponse{}); err != nil {
+		t.Fatalf("Unexpected error while receiving: %v", err)
+	}
+
+	// the caller abandons the stream without receiving until the end
+	cancel()
+
+	clientSpan := waitForGRPCClientSpan(t, mt)
+	if clientSpan.Tag(ext.ResourceName) != "/test.Streams/Subscribe" {
+		t.Errorf("Resource name incorrect, expected '/test.Streams/Subscribe' got '%v'", clientSpan.Tag(ext.ResourceName))
+	}
+	if clientSpan.Tag(ext.MapSpanError) == int32(1) {
+		t.Error("expected the canceled span not to be marked as errored")
+	}
+}
+
+// waitForGRPCClientSpan waits until the client span is finished, the span of a canceled stream is finished
+// in the background.
+func waitForGRPCClientSpan(t *testing.T, mt mocktracer.Tracer) *mocktracer.Span {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		for _, span := range mt.FinishedSpans() {
+			if span.OperationName() == grpcClientSpanName {
+				return span
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the client span to be finished")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGRPCDisabled(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm := NewApm(WithConfig(config.Config{
+		EnabledIntegrations: []string{config.IntegrationChi},
+		OutputPaths:         []string{filepath.Join(t.TempDir(), "apm.log")},
+	}))
+	if len(apm.GRPCDialOptions()) != 0 {
+		t.Errorf("expected no dial options with the grpc integration disabled, got %d", len(apm.GRPCDialOptions()))
+	}
+
+	client := newGRPCTestClient(t, apm)
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "panic"})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Status code incorrect, expected '%s' got '%s'", codes.Internal, status.Code(err))
+	}
+
+	if len(mt.FinishedSpans()) != 0 {
+		t.Errorf("expected no spans with the grpc integration disabled, got %d", len(mt.FinishedSpans()))
+	}
+}
@@ -0,0 +1,100 @@
+package apm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	httptrace "github.com/DataDog/dd-trace-go/contrib/net/http/v2"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/go-chi/chi/v5"
+)
+
+// WrapHandler traces every request to the handler. The resource name of the span is the method and the matched
+// route pattern, like "GET /orders/{id}", when the handler is a http.ServeMux or a chi router, otherwise the method.
+// The access log, panic recovery and span metrics of the Apm are applied like ConfigureOnRouter does.
+// Example:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("GET /orders/{id}", getOrder)
+//	http.ListenAndServe(":8080", apm.WrapHandler(mux))
+func (apm Apm) WrapHandler(handler http.Handler, opts ...httptrace.Option) http.Handler {
+	if apm.recovery != nil {
+		handler = apm.recoveryMiddleware(apm.recovery)(handler)
+	}
+	if apm.accessLog != nil {
+		handler = apm.accessLogMiddleware(apm.accessLog)(handler)
+	}
+
+	if !apm.integrationEnabled(config.IntegrationHttp) {
+		return handler
+	}
+
+	if apm.spanMetrics != nil {
+		handler = httpSpanMetricsMiddleware(handler)
+	}
+
+	return httptrace.WrapHandler(routeResourceHandler(handler), apm.service, "", opts...)
+}
+
+// ServeMux is a http.ServeMux that serves every request through WrapHandler, see NewServeMux.
+type ServeMux struct {
+	*http.ServeMux
+	handler http.Handler
+}
+
+func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mux.handler.ServeHTTP(w, r)
+}
+
+// NewServeMux creates a http.ServeMux which is served through WrapHandler, so every request is traced with the
+// method and the matched route pattern as resource name and gets the access log, panic recovery and span metrics
+// of the Apm.
+// Example:
+//
+//	mux := apm.NewServeMux()
+//	mux.HandleFunc("GET /orders/{id}", getOrder)
+//	http.ListenAndServe(":8080", mux)
+func (apm Apm) NewServeMux(opts ...httptrace.Option) *ServeMux {
+	serveMux := http.NewServeMux()
+	return &ServeMux{ServeMux: serveMux, handler: apm.WrapHandler(serveMux, opts...)}
+}
+
+// routeResourceHandler sets the resource name of the request span to the route pattern matched by the handler,
+// which is only known after the handler has routed the request. A chi route context is added to the request,
+// so a chi router records its route pattern in it instead of in a context that is not visible here.
+func routeResourceHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if chi.RouteContext(r.Context()) == nil {
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chi.NewRouteContext()))
+		}
+		handler.ServeHTTP(w, r)
+
+		span, ok := tracer.SpanFromContext(r.Context())
+		if !ok {
+			return
+		}
+
+		route := routePattern(r)
+		if route == "" {
+			span.SetTag(ext.ResourceName, r.Method)
+			return
+		}
+		span.SetTag(ext.HTTPRoute, route)
+		span.SetTag(ext.ResourceName, r.Method+" "+route)
+	})
+}
+
+// servePatternRoute returns the path of a http.ServeMux pattern, without the method and host.
+// For example "GET example.com/orders/{id}" results in "/orders/{id}".
+func servePatternRoute(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = strings.TrimLeft(path, " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
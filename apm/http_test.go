@@ -0,0 +1,183 @@
+package apm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestWrapHandler(t *testing.T) {
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	chiRouter := chi.NewRouter()
+	chiRouter.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name             string
+		handler          http.Handler
+		path             string
+		expectedResource string
+		expectedRoute    interface{}
+	}{
+		{
+			name:             "serve mux",
+			handler:          serveMux,
+			path:             "/orders/1",
+			expectedResource: "GET /orders/{id}",
+			expectedRoute:    "/orders/{id}",
+		},
+		{
+			name:             "chi router",
+			handler:          chiRouter,
+			path:             "/orders/1",
+			expectedResource: "GET /orders/{id}",
+			expectedRoute:    "/orders/{id}",
+		},
+		{
+			name:             "unmatched route",
+			handler:          serveMux,
+			path:             "/unknown/1",
+			expectedResource: "GET",
+			expectedRoute:    nil,
+		},
+		{
+			name:             "plain handler",
+			handler:          http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			path:             "/orders/1",
+			expectedResource: "GET",
+			expectedRoute:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			apm := NewApm()
+			apm.WrapHandler(tt.handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			spans := mt.FinishedSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			if spans[0].Tag(ext.ResourceName) != tt.expectedResource {
+				t.Errorf("Resource name incorrect, expected '%s' got '%v'", tt.expectedResource, spans[0].Tag(ext.ResourceName))
+			}
+			if spans[0].Tag(ext.HTTPRoute) != tt.expectedRoute {
+				t.Errorf("Route incorrect, expected '%v' got '%v'", tt.expectedRoute, spans[0].Tag(ext.HTTPRoute))
+			}
+		})
+	}
+}
+
+func TestNewServeMux(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm := NewApm(WithService("apm-test-service"))
+	mux := apm.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Tag(ext.ResourceName) != "GET /orders/{id}" {
+		t.Errorf("Resource name incorrect, expected 'GET /orders/{id}' got '%v'", spans[0].Tag(ext.ResourceName))
+	}
+	if spans[0].Tag(ext.ServiceName) != "apm-test-service" {
+		t.Errorf("Service name incorrect, expected 'apm-test-service' got '%v'", spans[0].Tag(ext.ServiceName))
+	}
+}
+
+func TestServeHandlersMiddlewares(t *testing.T) {
+	for _, enabledIntegrations := range [][]string{nil, {config.IntegrationChi}} {
+		mt := mocktracer.Start()
+
+		recorder := metrics.NewRecorder()
+		enableIntegrations := func(apm *Apm) {
+			apm.enabledIntegrations = enabledIntegrations
+		}
+		apm, readLogLines := newFileLogApm(t, enableIntegrations, WithMetricsClient(recorder), WithAccessLog(), WithRecovery(), WithSpanMetrics())
+
+		mux := apm.NewServeMux()
+		mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+			panic("out of stock")
+		})
+
+		response := httptest.NewRecorder()
+		mux.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+		apm.Shutdown(context.Background())
+		mt.Stop()
+
+		// the access log and recovery apply without the http integration as well, like for ConfigureOnRouter
+		if response.Code != http.StatusInternalServerError {
+			t.Errorf("Status code incorrect, expected '%d' got '%d'", http.StatusInternalServerError, response.Code)
+		}
+		var messages []interface{}
+		for _, logLine := range readLogLines() {
+			messages = append(messages, logLine["msg"])
+			if logLine["msg"] == "HTTP request" && logLine["http.route"] != "/orders/{id}" {
+				t.Errorf("Access log route incorrect, expected '/orders/{id}' got '%v'", logLine["http.route"])
+			}
+		}
+		if !slices.Equal(messages, []interface{}{"Recovered from panic", "HTTP request"}) {
+			t.Errorf("Log messages incorrect, expected '[Recovered from panic HTTP request]' got '%v'", messages)
+		}
+
+		if enabledIntegrations == nil {
+			assertSpanMetrics(t, recorder, httpServerSpanName, "GET /orders/{id}", 1, 1)
+			if spans := mt.FinishedSpans(); len(spans) != 1 || spans[0].Tag(ext.HTTPCode) != "500" {
+				t.Errorf("expected 1 span with status code '500', got '%v'", spans)
+			}
+		} else if len(recorder.Metrics()) != 0 || len(mt.FinishedSpans()) != 0 {
+			t.Errorf("expected no spans and span metrics with the http integration disabled, got %d spans and %d metrics", len(mt.FinishedSpans()), len(recorder.Metrics()))
+		}
+	}
+}
+
+func TestServeHandlersDisabled(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	apm := NewApm(WithConfig(config.Config{EnabledIntegrations: []string{config.IntegrationChi}}))
+
+	mux := apm.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	apm.WrapHandler(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	if len(mt.FinishedSpans()) != 0 {
+		t.Errorf("expected no spans with the http integration disabled, got %d", len(mt.FinishedSpans()))
+	}
+}
+
+func TestServePatternRoute(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "/orders/{id}", expected: "/orders/{id}"},
+		{pattern: "GET /orders/{id}", expected: "/orders/{id}"},
+		{pattern: "POST example.com/orders/", expected: "/orders/"},
+		{pattern: "example.com/", expected: "/"},
+	}
+
+	for _, tt := range tests {
+		if route := servePatternRoute(tt.pattern); route != tt.expected {
+			t.Errorf("Route of '%s' incorrect, expected '%s' got '%s'", tt.pattern, tt.expected, route)
+		}
+	}
+}
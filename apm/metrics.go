@@ -0,0 +1,105 @@
+package apm
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
+)
+
+// WithMetrics makes NewApm create a DogStatsD client as Metrics, which is closed again by Shutdown.
+// The metrics are tagged with the service, env and version of the Apm and sent to the agent host of the Apm,
+// any extra metrics options are applied after these.
+func WithMetrics(opts ...metrics.MetricsOption) ApmOption {
+	return func(apm *Apm) {
+		apm.startMetrics = true
+		apm.metricsOptions = append(apm.metricsOptions, opts...)
+	}
+}
+
+// WithMetricsClient sets the Metrics of the Apm, like a metrics.Recorder in tests.
+func WithMetricsClient(client metrics.Metrics) ApmOption {
+	return func(apm *Apm) {
+		apm.Metrics = client
+	}
+}
+
+// newMetrics creates the Metrics of the Apm, which drops every metric unless enabled with WithMetrics.
+func (apm Apm) newMetrics() metrics.Metrics {
+	if !apm.startMetrics {
+		return metrics.NewNoopMetrics()
+	}
+
+	client, err := metrics.NewMetrics(apm.metricsStartOptions()...)
+	if err != nil {
+		apm.Logger.Error(context.Background(), "Error starting metrics: %s", err)
+		return metrics.NewNoopMetrics()
+	}
+
+	return client
+}
+
+func (apm Apm) metricsStartOptions() []metrics.MetricsOption {
+	var opts []metrics.MetricsOption
+
+	if apm.service != "" {
+		opts = append(opts, metrics.WithService(apm.service))
+	}
+	if apm.env != "" {
+		opts = append(opts, metrics.WithEnv(apm.env))
+	}
+	if apm.version != "" {
+		opts = append(opts, metrics.WithVersion(apm.version))
+	}
+	if apm.agentAddr != "" {
+		if host, _, err := net.SplitHostPort(apm.agentAddr); err == nil {
+			opts = append(opts, metrics.WithAgentHost(host))
+		}
+	}
+
+	return append(opts, apm.metricsOptions...)
+}
+
+// startStatsReporter calls report every interval, until Shutdown is called. The Metrics of the Apm are used,
+// when metrics are disabled the reporter sends the statistics with its own DogStatsD client, which is created
+// like the one of WithMetrics and closed again when the reporter stops.
+func (apm Apm) startStatsReporter(interval time.Duration, report func(client metrics.Metrics)) {
+	client := apm.Metrics
+	_, noopMetrics := client.(metrics.NoopMetrics)
+	ownClient := noopMetrics || client == nil
+	if ownClient {
+		var err error
+		client, err = metrics.NewMetrics(apm.metricsStartOptions()...)
+		if err != nil {
+			apm.Logger.Error(context.Background(), "Error starting metrics, the statistics are not reported: %s", err)
+			return
+		}
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	apm.onShutdown(func() {
+		close(stop)
+		<-stopped
+	})
+
+	go func() {
+		defer close(stopped)
+		if ownClient {
+			defer client.Close()
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				report(client)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
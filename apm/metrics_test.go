@@ -0,0 +1,59 @@
+package apm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
+)
+
+func TestNewApmMetrics(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		apm := NewApm()
+
+		if _, ok := apm.Metrics.(metrics.NoopMetrics); !ok {
+			t.Errorf("Metrics incorrect, expected metrics.NoopMetrics got %T", apm.Metrics)
+		}
+	})
+
+	t.Run("with metrics client", func(t *testing.T) {
+		recorder := metrics.NewRecorder()
+		apm := NewApm(WithMetricsClient(recorder))
+		apm.Metrics.Count("orders.created", 1)
+
+		if len(recorder.Metrics()) != 1 {
+			t.Errorf("Recorded metrics incorrect, expected '1' got '%d'", len(recorder.Metrics()))
+		}
+	})
+
+	t.Run("with metrics", func(t *testing.T) {
+		listener, err := metrics.ListenUDP()
+		if err != nil {
+			t.Fatalf("Unexpected error while listening: %v", err)
+		}
+		defer listener.Close()
+
+		apm := NewApm(
+			WithService("apm-test-service"),
+			WithEnv("test"),
+			WithVersion("1.2.3"),
+			WithMetrics(metrics.WithAddr(listener.Addr()), metrics.WithTags("team:apm")),
+		)
+		apm.Metrics.Count("orders.created", 1, "payment_method:card")
+		if err := apm.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Unexpected error while shutting down: %v", err)
+		}
+
+		line, err := listener.NextWithPrefix("orders.created:", 2*time.Second)
+		if err != nil {
+			t.Fatalf("Unexpected error while reading the metrics: %v", err)
+		}
+		for _, tag := range []string{"service:apm-test-service", "env:test", "version:1.2.3", "team:apm", "payment_method:card"} {
+			if !strings.Contains(line, tag) {
+				t.Errorf("Metric tags incorrect, expected '%s' in '%s'", tag, line)
+			}
+		}
+	})
+}
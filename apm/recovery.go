@@ -0,0 +1,89 @@
+package apm
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type recoveryConfig struct {
+	response http.Handler
+}
+
+type RecoveryOption func(*recoveryConfig)
+
+// WithRecoveryResponse sets the handler that writes the response after a panic is recovered.
+// When not set, a plain text 500 Internal Server Error is written.
+// Example:
+//
+//	apm.WithRecoveryResponse(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		w.Header().Set("Content-Type", "application/json")
+//		w.WriteHeader(http.StatusInternalServerError)
+//		_, _ = w.Write([]byte(`{"error":"internal server error"}`))
+//	}))
+func WithRecoveryResponse(response http.Handler) RecoveryOption {
+	return func(cfg *recoveryConfig) {
+		cfg.response = response
+	}
+}
+
+// WithRecovery makes ConfigureOnRouter, WrapHandler and NewServeMux add the recovery middleware after the tracing
+// and access log middlewares, see RecoveryMiddleware.
+func WithRecovery(opts ...RecoveryOption) ApmOption {
+	return func(apm *Apm) {
+		apm.recovery = newRecoveryConfig(opts)
+	}
+}
+
+func newRecoveryConfig(opts []RecoveryOption) *recoveryConfig {
+	cfg := &recoveryConfig{
+		response: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// RecoveryMiddleware returns a chi middleware that recovers panics of the handlers. The panic value and stack are
+// logged with the trace correlation of the request span, the span is marked as errored with the same values,
+// and a 500 response is written. A http.ErrAbortHandler panic is not recovered, so the request is still aborted.
+// Example:
+//
+//	router.Use(apm.RecoveryMiddleware())
+func (apm Apm) RecoveryMiddleware(opts ...RecoveryOption) func(http.Handler) http.Handler {
+	return apm.recoveryMiddleware(newRecoveryConfig(opts))
+}
+
+func (apm Apm) recoveryMiddleware(cfg *recoveryConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				apm.Logger.ErrorErr(r.Context(), panicError(recovered), "Recovered from panic",
+					"http.method", r.Method,
+					"http.route", routePattern(r),
+				)
+				cfg.response.ServeHTTP(w, r)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// panicError converts a recovered panic value to an error, keeping the error chain when the value is an error.
+func panicError(recovered interface{}) error {
+	if err, ok := recovered.(error); ok {
+		return fmt.Errorf("panic: %w", err)
+	}
+	return fmt.Errorf("panic: %v", recovered)
+}
@@ -0,0 +1,110 @@
+package apm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	errPayment := errors.New("payment declined")
+
+	tests := []struct {
+		name            string
+		panicValue      interface{}
+		opts            []RecoveryOption
+		expectedMessage string
+		expectedBody    string
+	}{
+		{
+			name:            "panic with string",
+			panicValue:      "boom",
+			expectedMessage: "panic: boom",
+			expectedBody:    "Internal Server Error\n",
+		},
+		{
+			name:            "panic with error and custom response",
+			panicValue:      errPayment,
+			expectedMessage: "panic: payment declined",
+			opts: []RecoveryOption{WithRecoveryResponse(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error":"internal server error"}`))
+			}))},
+			expectedBody: `{"error":"internal server error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			apm, readLogLines := newFileLogApm(t, WithRecovery(tt.opts...))
+			router := chi.NewRouter()
+			apm.ConfigureOnRouter(router)
+			router.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+				panic(tt.panicValue)
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+			if recorder.Code != http.StatusInternalServerError {
+				t.Errorf("Status code incorrect, expected '%d' got '%d'", http.StatusInternalServerError, recorder.Code)
+			}
+			if recorder.Body.String() != tt.expectedBody {
+				t.Errorf("Body incorrect, expected '%s' got '%s'", tt.expectedBody, recorder.Body.String())
+			}
+
+			logLines := readLogLines()
+			if len(logLines) != 1 {
+				t.Fatalf("expected 1 log, got %d", len(logLines))
+			}
+			logLine := logLines[0]
+			if logLine["error.message"] != tt.expectedMessage {
+				t.Errorf("Field error.message incorrect, expected '%s' got '%v'", tt.expectedMessage, logLine["error.message"])
+			}
+			if logLine["http.route"] != "/orders/{id}" {
+				t.Errorf("Field http.route incorrect, expected '/orders/{id}' got '%v'", logLine["http.route"])
+			}
+			stack, _ := logLine["error.stack"].(string)
+			if !strings.Contains(stack, "TestRecoveryMiddleware") {
+				t.Errorf("expected error.stack to contain the panicking handler, got '%s'", stack)
+			}
+			if _, ok := logLine["dd.trace_id"]; !ok {
+				t.Error("expected the log to be correlated to the request span")
+			}
+
+			spans := mt.FinishedSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			if spans[0].Tag(ext.MapSpanError) != int32(1) {
+				t.Error("expected the request span to be marked as errored")
+			}
+			if spans[0].Tag(ext.ErrorStack) != stack {
+				t.Errorf("Error stack incorrect, expected '%s' got '%v'", stack, spans[0].Tag(ext.ErrorStack))
+			}
+		})
+	}
+}
+
+func TestRecoveryMiddlewareAbortHandler(t *testing.T) {
+	apm := NewApm()
+	handler := apm.RecoveryMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to be re-panicked, got '%v'", recovered)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,114 @@
+package apm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	redistrace "github.com/DataDog/dd-trace-go/contrib/redis/go-redis.v9/v2"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisSpanName         = "redis.command"
+	redisPipelineResource = "redis.pipeline"
+)
+
+type redisConfig struct {
+	service string
+}
+
+type RedisOption func(*redisConfig)
+
+// WithRedisService sets the service name of the redis spans, when not set the service of the tracer is used.
+func WithRedisService(service string) RedisOption {
+	return func(cfg *redisConfig) {
+		cfg.service = service
+	}
+}
+
+// ConfigureOnRedisClient traces the go-redis client with the go-redis integration of the tracer, which creates
+// a span for every command and pipeline. The command name is the resource of the span, the arguments of the
+// command, like keys and values, are obfuscated in the redis.raw_command tag.
+// Example:
+//
+//	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	apm.ConfigureOnRedisClient(client, apm.WithRedisService("orders-cache"))
+func (apm Apm) ConfigureOnRedisClient(client redis.UniversalClient, opts ...RedisOption) {
+	if !apm.integrationEnabled(config.IntegrationRedis) {
+		return
+	}
+
+	cfg := redisConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	traceOptions := []redistrace.ClientOption{redistrace.WithSkipRawCommand(true)}
+	if cfg.service != "" {
+		traceOptions = append(traceOptions, redistrace.WithService(cfg.service))
+	}
+	redistrace.WrapClient(client, traceOptions...)
+
+	// added after the tracing hook, so it runs inside the span of the command
+	client.AddHook(redisHook{service: cfg.service})
+}
+
+// redisHook sets the obfuscated command on the span of the go-redis integration and reports its span metrics.
+type redisHook struct {
+	service string
+}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		setRedisRawCommand(ctx, redisRawCommand(cmd))
+
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.observe(cmd.Name(), start, err)
+		return err
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		rawCommands := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			rawCommands[i] = redisRawCommand(cmd)
+		}
+		setRedisRawCommand(ctx, strings.Join(rawCommands, "\n"))
+
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.observe(redisPipelineResource, start, err)
+		return err
+	}
+}
+
+// observe reports the span metrics of a command, a missing key (redis.Nil) is not an error.
+func (h redisHook) observe(resource string, start time.Time, err error) {
+	observeSpan(redisSpanName, h.service, resource, start, err != nil && !errors.Is(err, redis.Nil))
+}
+
+func setRedisRawCommand(ctx context.Context, rawCommand string) {
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		span.SetTag(ext.RedisRawCommand, rawCommand)
+	}
+}
+
+// redisRawCommand returns the command name with every argument replaced by a question mark, like "SET ? ?".
+func redisRawCommand(cmd redis.Cmder) string {
+	rawCommand := strings.ToUpper(cmd.Name())
+	if args := len(cmd.Args()); args > 1 {
+		rawCommand += strings.Repeat(" ?", args-1)
+	}
+	return rawCommand
+}
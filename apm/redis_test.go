@@ -0,0 +1,118 @@
+package apm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestConfigureOnRedisClient(t *testing.T) {
+	tests := []struct {
+		name               string
+		run                func(ctx context.Context, client *redis.Client)
+		expectedResource   string
+		expectedRawCommand string
+		expectedError      bool
+	}{
+		{
+			name: "set",
+			run: func(ctx context.Context, client *redis.Client) {
+				client.Set(ctx, "order:1", "secret", 0)
+			},
+			expectedResource:   "set",
+			expectedRawCommand: "SET ? ?",
+		},
+		{
+			name: "get missing key",
+			run: func(ctx context.Context, client *redis.Client) {
+				client.Get(ctx, "order:2")
+			},
+			expectedResource:   "get",
+			expectedRawCommand: "GET ?",
+		},
+		{
+			name: "failing command",
+			run: func(ctx context.Context, client *redis.Client) {
+				client.Set(ctx, "order:1", "secret", 0)
+				client.Incr(ctx, "order:1")
+			},
+			expectedResource:   "incr",
+			expectedRawCommand: "INCR ?",
+			expectedError:      true,
+		},
+		{
+			name: "pipeline",
+			run: func(ctx context.Context, client *redis.Client) {
+				pipeline := client.Pipeline()
+				pipeline.Set(ctx, "order:1", "secret", 0)
+				pipeline.Get(ctx, "order:1")
+				_, _ = pipeline.Exec(ctx)
+			},
+			expectedResource:   redisPipelineResource,
+			expectedRawCommand: "SET ? ?\nGET ?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			server := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			defer client.Close()
+
+			apm := NewApm()
+			apm.ConfigureOnRedisClient(client, WithRedisService("apm-test-cache"))
+
+			tt.run(context.Background(), client)
+
+			spans := mt.FinishedSpans()
+			if len(spans) == 0 {
+				t.Fatal("expected at least 1 span, got 0")
+			}
+			span := spans[len(spans)-1]
+
+			if span.OperationName() != redisSpanName {
+				t.Errorf("Operation name incorrect, expected '%s' got '%s'", redisSpanName, span.OperationName())
+			}
+			if span.Tag(ext.ResourceName) != tt.expectedResource {
+				t.Errorf("Resource name incorrect, expected '%s' got '%v'", tt.expectedResource, span.Tag(ext.ResourceName))
+			}
+			if span.Tag(ext.RedisRawCommand) != tt.expectedRawCommand {
+				t.Errorf("Raw command incorrect, expected '%s' got '%v'", tt.expectedRawCommand, span.Tag(ext.RedisRawCommand))
+			}
+			if span.Tag(ext.ServiceName) != "apm-test-cache" {
+				t.Errorf("Service name incorrect, expected 'apm-test-cache' got '%v'", span.Tag(ext.ServiceName))
+			}
+			if span.Tag(ext.TargetHost) != server.Host() {
+				t.Errorf("Target host incorrect, expected '%s' got '%v'", server.Host(), span.Tag(ext.TargetHost))
+			}
+			if isErrored := span.Tag(ext.MapSpanError) == int32(1); isErrored != tt.expectedError {
+				t.Errorf("Span error flag incorrect, expected '%v' got '%v'", tt.expectedError, isErrored)
+			}
+		})
+	}
+}
+
+func TestConfigureOnRedisClientDisabled(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	apm := NewApm(WithConfig(config.Config{EnabledIntegrations: []string{config.IntegrationChi}}))
+	apm.ConfigureOnRedisClient(client)
+	client.Set(context.Background(), "order:1", "secret", 0)
+
+	if len(mt.FinishedSpans()) != 0 {
+		t.Errorf("expected no spans with the redis integration disabled, got %d", len(mt.FinishedSpans()))
+	}
+}
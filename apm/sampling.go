@@ -0,0 +1,196 @@
+package apm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+)
+
+// traceRateLimitEnv is read by the tracer when it starts, it has no start option for the rate limit.
+const traceRateLimitEnv = "DD_TRACE_RATE_LIMIT"
+
+// WithSampleRate sets the rate between 0 and 1 of the traces that are kept when no sampling rule matches,
+// like DD_TRACE_SAMPLE_RATE. The traces are kept up to the rate limit, see WithTraceRateLimit.
+// Example:
+//
+//	apm := apm.NewApm(apm.WithTracer(), apm.WithSampleRate(0.1))
+func WithSampleRate(rate float64) ApmOption {
+	return func(apm *Apm) {
+		if err := validateSampleRate(rate); err != nil {
+			apm.samplingErrors = append(apm.samplingErrors, err)
+			return
+		}
+		apm.sampleRate = &rate
+	}
+}
+
+// WithSamplingRules adds rules that set the rate of the traces that are kept by the service, operation name,
+// resource and tags of their root span. The patterns are globs, the first matching rule is used and the sample rate
+// applies to the traces that match no rule. The traces kept by a rule are limited by the rate limit.
+// Example:
+//
+//	apm := apm.NewApm(apm.WithTracer(), apm.WithSamplingRules(
+//		tracer.Rule{ResourceGlob: "GET /health", Rate: 0},
+//		tracer.Rule{ServiceGlob: "orders", Tags: map[string]string{"http.useragent": "*bot*"}, Rate: 0.01},
+//		tracer.Rule{ServiceGlob: "orders", Rate: 0.5},
+//	))
+func WithSamplingRules(rules ...tracer.Rule) ApmOption {
+	return func(apm *Apm) {
+		for _, rule := range rules {
+			if err := validateSamplingRule(rule, false); err != nil {
+				apm.samplingErrors = append(apm.samplingErrors, err)
+				continue
+			}
+			apm.samplingRules = append(apm.samplingRules, rule)
+		}
+	}
+}
+
+// WithSpanSamplingRules adds rules that keep single spans of the traces that are dropped, like the spans of
+// a critical operation. MaxPerSecond limits the spans kept by a rule, 0 keeps every matching span.
+// Example:
+//
+//	apm := apm.NewApm(apm.WithTracer(), apm.WithSpanSamplingRules(
+//		tracer.Rule{ServiceGlob: "orders", NameGlob: "payment.*", Rate: 1, MaxPerSecond: 50},
+//	))
+func WithSpanSamplingRules(rules ...tracer.Rule) ApmOption {
+	return func(apm *Apm) {
+		for _, rule := range rules {
+			if err := validateSamplingRule(rule, true); err != nil {
+				apm.samplingErrors = append(apm.samplingErrors, err)
+				continue
+			}
+			apm.spanSamplingRules = append(apm.spanSamplingRules, rule)
+		}
+	}
+}
+
+// WithTraceRateLimit sets the maximum number of traces per second kept by the sampling rules and the sample rate,
+// 100 by default. The tracer only reads the limit from DD_TRACE_RATE_LIMIT, which is set for the process right
+// before NewApm starts the tracer, so it has no effect without WithTracer.
+// Example:
+//
+//	apm := apm.NewApm(apm.WithTracer(), apm.WithSampleRate(1), apm.WithTraceRateLimit(50))
+func WithTraceRateLimit(perSecond float64) ApmOption {
+	return func(apm *Apm) {
+		if perSecond < 0 {
+			apm.samplingErrors = append(apm.samplingErrors, fmt.Errorf("invalid trace rate limit %v, expected a value of at least 0", perSecond))
+			return
+		}
+		apm.traceRateLimit = &perSecond
+	}
+}
+
+func validateSampleRate(rate float64) error {
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("invalid sample rate %v, expected a value between 0 and 1", rate)
+	}
+	return nil
+}
+
+// validateSamplingRule checks the rate of a rule, only span sampling rules have a rate limit of their own.
+func validateSamplingRule(rule tracer.Rule, spanRule bool) error {
+	var errs []error
+
+	if err := validateSampleRate(rule.Rate); err != nil {
+		errs = append(errs, err)
+	}
+	if rule.MaxPerSecond < 0 {
+		errs = append(errs, fmt.Errorf("invalid max per second %v, expected a value of at least 0", rule.MaxPerSecond))
+	}
+	if !spanRule && rule.MaxPerSecond != 0 {
+		errs = append(errs, errors.New("max per second is only supported by span sampling rules, use WithTraceRateLimit for traces"))
+	}
+	for key := range rule.Tags {
+		if key == "" {
+			errs = append(errs, errors.New("invalid tag pattern with an empty key"))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid sampling rule %+v: %w", rule, errors.Join(errs...))
+	}
+	return nil
+}
+
+// samplingStartOptions returns the sampling rules of the tracer, the sample rate is the last rule matching every trace.
+func (apm Apm) samplingStartOptions() []tracer.StartOption {
+	traceRules := apm.samplingRules
+	if apm.sampleRate != nil {
+		traceRules = append(traceRules[:len(traceRules):len(traceRules)], tracer.Rule{Rate: *apm.sampleRate})
+	}
+
+	var opts []tracer.StartOption
+	if len(traceRules) > 0 {
+		opts = append(opts, tracer.WithSamplingRules(tracer.TraceSamplingRules(traceRules...)))
+	}
+	if len(apm.spanSamplingRules) > 0 {
+		opts = append(opts, tracer.WithSamplingRules(tracer.SpanSamplingRules(apm.spanSamplingRules...)))
+	}
+	return opts
+}
+
+// logSamplingErrors logs the invalid sampling options, which are ignored, see Validate.
+func (apm Apm) logSamplingErrors() {
+	for _, err := range apm.samplingErrors {
+		apm.Logger.Error(context.Background(), "Ignoring sampling option: %s", err)
+	}
+}
+
+// setTraceRateLimit sets the rate limit for the tracer, it is only called right before the tracer is started.
+func (apm Apm) setTraceRateLimit() {
+	if apm.traceRateLimit == nil {
+		return
+	}
+
+	if err := os.Setenv(traceRateLimitEnv, strconv.FormatFloat(*apm.traceRateLimit, 'f', -1, 64)); err != nil {
+		apm.Logger.Error(context.Background(), "Error setting the trace rate limit: %s", err)
+	}
+}
+
+// Validate reports every invalid option passed to NewApm in the returned error, like a sample rate outside 0 and 1.
+// NewApm ignores and logs the invalid options, check Validate to fail at boot instead.
+// Example:
+//
+//	apm := apm.NewApm(apm.WithTracer(), apm.WithSampleRate(rate))
+//	if err := apm.Validate(); err != nil {
+//		panic(err)
+//	}
+func (apm Apm) Validate() error {
+	return errors.Join(apm.samplingErrors...)
+}
+
+// KeepTrace makes the tracer keep the trace of the span in the context, regardless of the sampling rules,
+// like for debug requests. It reports whether the context has a span.
+// Example:
+//
+//	if r.Header.Get("X-Debug-Trace") == "1" {
+//		apm.KeepTrace(r.Context())
+//	}
+func KeepTrace(ctx context.Context) bool {
+	return setSamplingDecision(ctx, ext.ManualKeep)
+}
+
+// DropTrace makes the tracer drop the trace of the span in the context, regardless of the sampling rules,
+// like for health checks. It reports whether the context has a span.
+// Example:
+//
+//	apm.DropTrace(r.Context())
+func DropTrace(ctx context.Context) bool {
+	return setSamplingDecision(ctx, ext.ManualDrop)
+}
+
+func setSamplingDecision(ctx context.Context, decision string) bool {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	span.SetTag(decision, true)
+	return true
+}
@@ -0,0 +1,242 @@
+package apm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+)
+
+func TestSamplingOptions(t *testing.T) {
+	tests := []struct {
+		name                      string
+		opts                      []ApmOption
+		expectedSampleRate        *float64
+		expectedSamplingRules     int
+		expectedSpanSamplingRules int
+		expectedErrors            []string
+	}{
+		{
+			name:               "sample rate",
+			opts:               []ApmOption{WithSampleRate(0.25)},
+			expectedSampleRate: floatPointer(0.25),
+		},
+		{
+			name:           "invalid sample rate",
+			opts:           []ApmOption{WithSampleRate(1.5)},
+			expectedErrors: []string{"invalid sample rate 1.5, expected a value between 0 and 1"},
+		},
+		{
+			name: "sampling rules",
+			opts: []ApmOption{WithSamplingRules(
+				tracer.Rule{ServiceGlob: "orders", Rate: 0.5},
+				tracer.Rule{ResourceGlob: "GET /health", Rate: 0},
+				tracer.Rule{Tags: map[string]string{"http.useragent": "*bot*"}, Rate: 0.01},
+			)},
+			expectedSamplingRules: 3,
+		},
+		{
+			name: "invalid sampling rules",
+			opts: []ApmOption{WithSamplingRules(
+				tracer.Rule{ServiceGlob: "orders", Rate: -0.5},
+				tracer.Rule{ServiceGlob: "orders", Rate: 1, MaxPerSecond: 10},
+				tracer.Rule{Tags: map[string]string{"": "value"}, Rate: 1},
+				tracer.Rule{ServiceGlob: "catalog", Rate: 1},
+			)},
+			expectedSamplingRules: 1,
+			expectedErrors: []string{
+				"invalid sample rate -0.5, expected a value between 0 and 1",
+				"max per second is only supported by span sampling rules",
+				"invalid tag pattern with an empty key",
+			},
+		},
+		{
+			name: "span sampling rules",
+			opts: []ApmOption{WithSpanSamplingRules(
+				tracer.Rule{ServiceGlob: "orders", NameGlob: "payment.*", Rate: 1, MaxPerSecond: 50},
+				tracer.Rule{ServiceGlob: "orders", Rate: 1, MaxPerSecond: -1},
+			)},
+			expectedSpanSamplingRules: 1,
+			expectedErrors:            []string{"invalid max per second -1, expected a value of at least 0"},
+		},
+		{
+			name:           "invalid trace rate limit",
+			opts:           []ApmOption{WithTraceRateLimit(-1)},
+			expectedErrors: []string{"invalid trace rate limit -1, expected a value of at least 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apm, readLogLines := newFileLogApm(t, tt.opts...)
+
+			if (apm.sampleRate == nil) != (tt.expectedSampleRate == nil) || (apm.sampleRate != nil && *apm.sampleRate != *tt.expectedSampleRate) {
+				t.Errorf("Sample rate incorrect, expected '%v' got '%v'", tt.expectedSampleRate, apm.sampleRate)
+			}
+			if len(apm.samplingRules) != tt.expectedSamplingRules {
+				t.Errorf("Sampling rules incorrect, expected '%d' got '%d'", tt.expectedSamplingRules, len(apm.samplingRules))
+			}
+			if len(apm.spanSamplingRules) != tt.expectedSpanSamplingRules {
+				t.Errorf("Span sampling rules incorrect, expected '%d' got '%d'", tt.expectedSpanSamplingRules, len(apm.spanSamplingRules))
+			}
+
+			err := apm.Validate()
+			if (err == nil) != (len(tt.expectedErrors) == 0) {
+				t.Errorf("Validate incorrect, expected %d errors got '%v'", len(tt.expectedErrors), err)
+			}
+			for _, expected := range tt.expectedErrors {
+				if err != nil && !strings.Contains(err.Error(), expected) {
+					t.Errorf("Validate error incorrect, expected '%s' in '%s'", expected, err)
+				}
+			}
+
+			logLines := readLogLines()
+			if len(logLines) != len(apm.samplingErrors) {
+				t.Errorf("expected a log line per invalid option, got %d lines for %d errors", len(logLines), len(apm.samplingErrors))
+			}
+			var logged string
+			for _, logLine := range logLines {
+				if logLine["status"] != "error" {
+					t.Errorf("Log status incorrect, expected 'error' got '%v'", logLine["status"])
+				}
+				logged += logLine["msg"].(string) + "\n"
+			}
+			for _, expected := range tt.expectedErrors {
+				if !strings.Contains(logged, expected) {
+					t.Errorf("Logged errors incorrect, expected '%s' in '%s'", expected, logged)
+				}
+			}
+		})
+	}
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+func TestSamplingRules(t *testing.T) {
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer agent.Close()
+
+	tests := []struct {
+		name             string
+		opts             []ApmOption
+		service          string
+		resource         string
+		expectedPriority int
+	}{
+		{
+			name:             "matching rule",
+			opts:             []ApmOption{WithSamplingRules(tracer.Rule{ServiceGlob: "orders", ResourceGlob: "GET /health", Rate: 0}), WithSampleRate(1)},
+			service:          "orders",
+			resource:         "GET /health",
+			expectedPriority: ext.PriorityUserReject,
+		},
+		{
+			name:             "sample rate when no rule matches",
+			opts:             []ApmOption{WithSamplingRules(tracer.Rule{ServiceGlob: "orders", ResourceGlob: "GET /health", Rate: 0}), WithSampleRate(1)},
+			service:          "orders",
+			resource:         "GET /orders",
+			expectedPriority: ext.PriorityUserKeep,
+		},
+		{
+			name:             "rate limit",
+			opts:             []ApmOption{WithSampleRate(1), WithTraceRateLimit(0)},
+			service:          "orders",
+			resource:         "GET /orders",
+			expectedPriority: ext.PriorityUserReject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(traceRateLimitEnv, "")
+
+			apm := NewApm(append([]ApmOption{WithTracer(), WithAgentAddr(strings.TrimPrefix(agent.URL, "http://"))}, tt.opts...)...)
+			defer apm.Shutdown(context.Background())
+
+			span, _ := tracer.StartSpanFromContext(context.Background(), "http.request", tracer.ServiceName(tt.service), tracer.ResourceName(tt.resource))
+			priority, ok := span.Context().SamplingPriority()
+			span.Finish()
+
+			if !ok || priority != tt.expectedPriority {
+				t.Errorf("Sampling priority incorrect, expected '%d' got '%d' (set %v)", tt.expectedPriority, priority, ok)
+			}
+		})
+	}
+}
+
+func TestWithTraceRateLimit(t *testing.T) {
+	t.Setenv(traceRateLimitEnv, "")
+
+	// without the tracer the environment of the process is left alone
+	NewApm(WithTraceRateLimit(12.5))
+	if limit := os.Getenv(traceRateLimitEnv); limit != "" {
+		t.Errorf("Trace rate limit incorrect, expected '' without the tracer got '%s'", limit)
+	}
+
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer agent.Close()
+
+	apm := NewApm(WithTracer(), WithAgentAddr(strings.TrimPrefix(agent.URL, "http://")), WithTraceRateLimit(12.5))
+	defer apm.Shutdown(context.Background())
+
+	if limit := os.Getenv(traceRateLimitEnv); limit != "12.5" {
+		t.Errorf("Trace rate limit incorrect, expected '12.5' got '%s'", limit)
+	}
+}
+
+func TestKeepAndDropTrace(t *testing.T) {
+	tests := []struct {
+		name             string
+		decide           func(ctx context.Context) bool
+		expectedPriority int
+	}{
+		{
+			name:             "keep",
+			decide:           KeepTrace,
+			expectedPriority: ext.PriorityUserKeep,
+		},
+		{
+			name:             "drop",
+			decide:           DropTrace,
+			expectedPriority: ext.PriorityUserReject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			if tt.decide(context.Background()) {
+				t.Error("expected no decision without a span in the context")
+			}
+
+			root, ctx := tracer.StartSpanFromContext(context.Background(), "http.request")
+			child, childCtx := tracer.StartSpanFromContext(ctx, "orders.process")
+			if !tt.decide(childCtx) {
+				t.Error("expected a decision with a span in the context")
+			}
+			child.Finish()
+			root.Finish()
+
+			for _, span := range mt.FinishedSpans() {
+				priority, ok := span.Context().SamplingPriority()
+				if !ok || priority != tt.expectedPriority {
+					t.Errorf("Sampling priority of %s incorrect, expected '%d' got '%d' (set %v)", span.OperationName(), tt.expectedPriority, priority, ok)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package apm
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	spanMetricPrefix   = "apm."
+	httpServerSpanName = "http.request"
+)
+
+// WithSpanMetrics makes the apm report the hits, errors and duration of the spans of its integrations to the
+// Metrics of the Apm, so dashboards keep working when traces are sampled away. The metrics are computed before
+// sampling, in the hooks the apm already runs around a span, for the spans of Trace, TraceValue, WrapHandler,
+// NewServeMux, ConfigureOnRouter, the gRPC server options and ConfigureOnRedisClient. The spans of the gRPC dial
+// options and the database spans of ConfigureOnSQLClient, ConfigureOnSQLXClient, ConfigureOnGormClient and
+// ConfigureOnPgxPool are not covered.
+// The metrics are dropped unless WithMetrics is used.
+// Example:
+//
+//	apm := apm.NewApm(apm.WithMetrics(), apm.WithSpanMetrics())
+func WithSpanMetrics() ApmOption {
+	return func(apm *Apm) {
+		apm.startSpanMetrics = true
+	}
+}
+
+// spanMetrics reports the RED metrics of finished spans per operation and resource, like the trace metrics
+// of the agent but under the "apm." prefix: apm.<operation>.hits, apm.<operation>.errors and a
+// apm.<operation>.duration histogram in seconds. Spans with their own service, like a database,
+// are tagged with span.service, the other spans are tagged with the service of the metrics.
+// A nil spanMetrics reports nothing.
+type spanMetrics struct {
+	client metrics.Metrics
+}
+
+// activeSpanMetrics are the span metrics of the Apm created with WithSpanMetrics. Like the tracer they are
+// global, so the spans of Trace and TraceValue, which are not methods of an Apm, are reported as well.
+var activeSpanMetrics atomic.Pointer[spanMetrics]
+
+// newSpanMetrics returns nil unless span metrics are enabled with WithSpanMetrics,
+// the enabled span metrics become the active span metrics.
+func (apm Apm) newSpanMetrics() *spanMetrics {
+	if !apm.startSpanMetrics {
+		return nil
+	}
+
+	m := &spanMetrics{client: apm.Metrics}
+	activeSpanMetrics.Store(m)
+	return m
+}
+
+// stopSpanMetrics stops reporting to the span metrics of the Apm, unless another Apm made its span metrics active.
+func (apm Apm) stopSpanMetrics() {
+	if apm.spanMetrics != nil {
+		activeSpanMetrics.CompareAndSwap(apm.spanMetrics, nil)
+	}
+}
+
+// observeSpan reports a finished span to the active span metrics, it is the single hook the integrations
+// call when they finish a span.
+func observeSpan(operation string, service string, resource string, start time.Time, isError bool) {
+	activeSpanMetrics.Load().observe(operation, service, resource, start, isError)
+}
+
+// observe reports a span that started at start, service is empty for spans of the service itself.
+func (m *spanMetrics) observe(operation string, service string, resource string, start time.Time, isError bool) {
+	if m == nil {
+		return
+	}
+
+	tags := []string{"resource:" + spanMetricTagValue(resource)}
+	if service != "" {
+		tags = append(tags, "span.service:"+spanMetricTagValue(service))
+	}
+
+	m.client.Count(spanMetricPrefix+operation+".hits", 1, tags...)
+	if isError {
+		m.client.Count(spanMetricPrefix+operation+".errors", 1, tags...)
+	}
+	m.client.Histogram(spanMetricPrefix+operation+".duration", time.Since(start).Seconds(), tags...)
+}
+
+// spanMetricTagValue replaces the characters that separate the tags and fields of a DogStatsD datagram.
+func spanMetricTagValue(value string) string {
+	return spanMetricTagReplacer.Replace(value)
+}
+
+var spanMetricTagReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", "_", "\r", "_")
+
+// httpSpanMetricsMiddleware reports the request spans of a router, with the method and route pattern as resource
+// like the tracing middleware. 5xx responses are errors.
+func httpSpanMetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := wrapResponseWriter(w, r)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		observeSpan(httpServerSpanName, "", httpResource(r), start, status >= http.StatusInternalServerError)
+	})
+}
+
+// httpResource returns the method and the matched route pattern of the request, or only the method
+// when no route matched.
+func httpResource(r *http.Request) string {
+	if route := routePattern(r); route != "" {
+		return r.Method + " " + route
+	}
+	return r.Method
+}
+
+// wrapResponseWriter returns the response writer when it already records the status, like for the access log
+// inside the span metrics middleware, so a request is wrapped once.
+func wrapResponseWriter(w http.ResponseWriter, r *http.Request) middleware.WrapResponseWriter {
+	if ww, ok := w.(middleware.WrapResponseWriter); ok {
+		return ww
+	}
+	return middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+}
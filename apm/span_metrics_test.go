@@ -0,0 +1,297 @@
+package apm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/metrics"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// assertSpanMetrics checks the hits and errors of an operation and resource, and that their duration is reported.
+func assertSpanMetrics(t *testing.T, recorder *metrics.Recorder, operation string, resource string, expectedHits float64, expectedErrors float64) {
+	t.Helper()
+
+	values := map[string]float64{}
+	for _, metric := range recorder.Metrics() {
+		if slices.Contains(metric.Tags, "resource:"+resource) {
+			values[metric.Name] += metric.Value
+		}
+	}
+
+	if hits := values[spanMetricPrefix+operation+".hits"]; hits != expectedHits {
+		t.Errorf("Hits of %s '%s' incorrect, expected '%v' got '%v'", operation, resource, expectedHits, hits)
+	}
+	if errs := values[spanMetricPrefix+operation+".errors"]; errs != expectedErrors {
+		t.Errorf("Errors of %s '%s' incorrect, expected '%v' got '%v'", operation, resource, expectedErrors, errs)
+	}
+	if _, ok := values[spanMetricPrefix+operation+".duration"]; !ok {
+		t.Errorf("expected the duration of %s '%s', got none", operation, resource)
+	}
+}
+
+// useSpanMetrics makes the span metrics of the recorder active for the test, like NewApm with WithSpanMetrics.
+func useSpanMetrics(t *testing.T, recorder *metrics.Recorder) {
+	previous := activeSpanMetrics.Swap(&spanMetrics{client: recorder})
+	t.Cleanup(func() {
+		activeSpanMetrics.Store(previous)
+	})
+}
+
+func TestSpanMetricsObserve(t *testing.T) {
+	recorder := metrics.NewRecorder()
+	m := &spanMetrics{client: recorder}
+
+	m.observe(redisSpanName, "orders-cache", "GET", time.Now().Add(-time.Second), true)
+
+	expectedTags := []string{"resource:GET", "span.service:orders-cache"}
+	expected := []struct {
+		metricType metrics.MetricType
+		name       string
+	}{
+		{metricType: metrics.TypeCount, name: "apm.redis.command.hits"},
+		{metricType: metrics.TypeCount, name: "apm.redis.command.errors"},
+		{metricType: metrics.TypeHistogram, name: "apm.redis.command.duration"},
+	}
+	recorded := recorder.Metrics()
+	if len(recorded) != len(expected) {
+		t.Fatalf("expected %d metrics, got %d", len(expected), len(recorded))
+	}
+	for i, metric := range recorded {
+		if metric.Type != expected[i].metricType || metric.Name != expected[i].name {
+			t.Errorf("Metric incorrect, expected %s '%s' got %s '%s'", expected[i].metricType, expected[i].name, metric.Type, metric.Name)
+		}
+		if !slices.Equal(metric.Tags, expectedTags) {
+			t.Errorf("Metric tags incorrect, expected '%v' got '%v'", expectedTags, metric.Tags)
+		}
+	}
+	if duration := recorded[2].Value; duration < 1 {
+		t.Errorf("Duration incorrect, expected at least '1' second got '%v'", duration)
+	}
+
+	// a nil spanMetrics, when span metrics are disabled, reports nothing
+	var disabled *spanMetrics
+	disabled.observe(redisSpanName, "", "GET", time.Now(), false)
+}
+
+func TestSpanMetricsTagValues(t *testing.T) {
+	recorder := metrics.NewRecorder()
+	m := &spanMetrics{client: recorder}
+
+	m.observe("orders.update", "orders|db", "UPDATE orders SET status = 'paid', paid = true\nWHERE id = 1", time.Now(), false)
+
+	expectedTags := []string{"resource:UPDATE orders SET status = 'paid'_ paid = true_WHERE id = 1", "span.service:orders_db"}
+	if metric, ok := recorder.Last("apm.orders.update.hits"); !ok || !slices.Equal(metric.Tags, expectedTags) {
+		t.Errorf("Hits tags incorrect, expected '%v' got '%v'", expectedTags, metric.Tags)
+	}
+}
+
+func TestSpanMetricsTrace(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	recorder := metrics.NewRecorder()
+	useSpanMetrics(t, recorder)
+
+	ctx := context.Background()
+	_ = Trace(ctx, "orders.process", func(ctx context.Context) error {
+		return nil
+	}, tracer.ResourceName("ProcessOrder"))
+	_ = Trace(ctx, "orders.process", func(ctx context.Context) error {
+		return errors.New("payment declined")
+	}, tracer.ResourceName("ProcessOrder"))
+	_, _ = TraceValue(ctx, "orders.get", func(ctx context.Context) (int, error) {
+		return 42, nil
+	}, tracer.ServiceName("orders-db"))
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+		_ = Trace(ctx, "orders.process", func(ctx context.Context) error {
+			panic("out of stock")
+		}, tracer.ResourceName("ProcessOrder"))
+	}()
+
+	// the name of the span is the resource when no resource is set
+	assertSpanMetrics(t, recorder, "orders.process", "ProcessOrder", 3, 2)
+	assertSpanMetrics(t, recorder, "orders.get", "orders.get", 1, 0)
+
+	if metric, ok := recorder.Last("apm.orders.get.hits"); !ok || !slices.Contains(metric.Tags, "span.service:orders-db") {
+		t.Errorf("Hits tags incorrect, expected 'span.service:orders-db' in '%v'", metric.Tags)
+	}
+}
+
+func TestSpanMetricsHTTP(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	recorder := metrics.NewRecorder()
+	apm := NewApm(WithMetricsClient(recorder), WithSpanMetrics())
+	defer apm.Shutdown(context.Background())
+
+	router := chi.NewRouter()
+	apm.ConfigureOnRouter(router)
+	router.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	router.Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("GET /invoices/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/2", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	apm.WrapHandler(serveMux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/invoices/1", nil))
+
+	assertSpanMetrics(t, recorder, httpServerSpanName, "GET /orders/{id}", 2, 0)
+	assertSpanMetrics(t, recorder, httpServerSpanName, "POST /orders", 1, 1)
+	assertSpanMetrics(t, recorder, httpServerSpanName, "GET /invoices/{id}", 1, 0)
+}
+
+func TestSpanMetricsGRPC(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	recorder := metrics.NewRecorder()
+	apm := NewApm(WithMetricsClient(recorder), WithSpanMetrics())
+	defer apm.Shutdown(context.Background())
+	client := newGRPCTestClient(t, apm)
+
+	for _, service := range []string{"orders", "missing", "unavailable"} {
+		_, _ = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+	}
+
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "orders"})
+	if err != nil {
+		t.Fatalf("Unexpected error while watching: %v", err)
+	}
+	for err == nil {
+		_, err = stream.Recv()
+	}
+
+	// only the Unavailable code is an error, NotFound is caused by the caller
+	assertSpanMetrics(t, recorder, grpcServerSpanName, "/grpc.health.v1.Health/Check", 3, 1)
+	assertSpanMetrics(t, recorder, grpcServerSpanName, "/grpc.health.v1.Health/Watch", 1, 0)
+
+	// the client spans are finished by the gRPC integration of the tracer only
+	for _, metric := range recorder.Metrics() {
+		if strings.HasPrefix(metric.Name, spanMetricPrefix+grpcClientSpanName) {
+			t.Errorf("expected no span metrics of the client calls, got '%s'", metric.Name)
+		}
+	}
+}
+
+func TestSpanMetricsRedis(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	recorder := metrics.NewRecorder()
+	apm := NewApm(WithMetricsClient(recorder), WithSpanMetrics())
+	defer apm.Shutdown(context.Background())
+	apm.ConfigureOnRedisClient(client, WithRedisService("orders-cache"))
+
+	// the first command opens the connection with a pipeline of its own
+	ctx := context.Background()
+	client.Ping(ctx)
+	recorder.Reset()
+
+	client.Set(ctx, "order:1", "paid", 0)
+	client.Get(ctx, "order:2")
+	client.Incr(ctx, "order:1")
+	_, _ = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "order:1")
+		return nil
+	})
+
+	// a missing key (redis.Nil) is not an error
+	assertSpanMetrics(t, recorder, redisSpanName, "set", 1, 0)
+	assertSpanMetrics(t, recorder, redisSpanName, "get", 1, 0)
+	assertSpanMetrics(t, recorder, redisSpanName, "incr", 1, 1)
+	assertSpanMetrics(t, recorder, redisSpanName, redisPipelineResource, 1, 0)
+
+	if metric, ok := recorder.Last("apm.redis.command.hits"); !ok || !slices.Contains(metric.Tags, "span.service:orders-cache") {
+		t.Errorf("Hits tags incorrect, expected 'span.service:orders-cache' in '%v'", metric.Tags)
+	}
+}
+
+func TestSpanMetricsDogStatsD(t *testing.T) {
+	listener, err := metrics.ListenUDP()
+	if err != nil {
+		t.Fatalf("Unexpected error while listening: %v", err)
+	}
+	defer listener.Close()
+
+	apm := NewApm(WithMetrics(metrics.WithAddr(listener.Addr())), WithSpanMetrics())
+
+	query := "UPDATE orders SET status = 'paid', paid = true WHERE id = 1"
+	_ = Trace(context.Background(), "orders.update", func(ctx context.Context) error {
+		return nil
+	}, tracer.ResourceName(query), tracer.ServiceName("orders|db"))
+
+	// the metrics are flushed when Shutdown closes the client
+	if err := apm.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected error while shutting down: %v", err)
+	}
+
+	line, err := listener.NextWithPrefix("apm.orders.update.hits:", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error while reading the metrics: %v", err)
+	}
+
+	// a datagram is "name:value|type|#tag,tag", a comma or pipe in a tag would split it
+	fields := strings.Split(line, "|")
+	if len(fields) != 3 || !strings.HasPrefix(fields[2], "#") {
+		t.Fatalf("Datagram incorrect, expected 'name:value|type|#tags' got '%s'", line)
+	}
+	tags := strings.Split(strings.TrimPrefix(fields[2], "#"), ",")
+	for _, expectedTag := range []string{"resource:UPDATE orders SET status = 'paid'_ paid = true WHERE id = 1", "span.service:orders_db"} {
+		if !slices.Contains(tags, expectedTag) {
+			t.Errorf("Datagram tags incorrect, expected '%s' in '%v'", expectedTag, tags)
+		}
+	}
+}
+
+func TestSpanMetricsDisabled(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	recorder := metrics.NewRecorder()
+	apm := NewApm(WithMetricsClient(recorder))
+
+	router := chi.NewRouter()
+	apm.ConfigureOnRouter(router)
+	router.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	if apm.spanMetrics != nil {
+		t.Error("expected no span metrics without WithSpanMetrics")
+	}
+	if len(recorder.Metrics()) != 0 {
+		t.Errorf("expected no metrics without WithSpanMetrics, got %d", len(recorder.Metrics()))
+	}
+
+	_ = Trace(context.Background(), "orders.process", func(ctx context.Context) error {
+		return nil
+	})
+	if len(recorder.Metrics()) != 0 {
+		t.Errorf("expected no metrics of Trace without WithSpanMetrics, got %d", len(recorder.Metrics()))
+	}
+}
@@ -0,0 +1,68 @@
+package apm
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/internal/openspans"
+)
+
+// Trace runs fn inside a new span, which is finished with the error returned by fn.
+// A panic in fn is recorded on the span before it is re-panicked. The span is reported to the span metrics,
+// when enabled with WithSpanMetrics.
+// Example:
+//
+//	err := apm.Trace(ctx, "orders.process", func(ctx context.Context) error {
+//		return processOrder(ctx, order)
+//	}, tracer.ResourceName("ProcessOrder"))
+func Trace(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...tracer.StartSpanOption) error {
+	_, err := TraceValue(ctx, name, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts...)
+
+	return err
+}
+
+// TraceValue runs fn inside a new span like Trace, and returns the value returned by fn.
+// Example:
+//
+//	order, err := apm.TraceValue(ctx, "orders.get", func(ctx context.Context) (Order, error) {
+//		return repository.GetOrder(ctx, id)
+//	}, tracer.SpanType(ext.SpanTypeSQL), tracer.Tag("order.id", id))
+func TraceValue[T any](ctx context.Context, name string, fn func(ctx context.Context) (T, error), opts ...tracer.StartSpanOption) (value T, err error) {
+	start := time.Now()
+	span, spanContext := tracer.StartSpanFromContext(ctx, name, opts...)
+	spanContext = openspans.ContextWithSpan(spanContext, span)
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			span.SetTag(ext.ErrorStack, string(debug.Stack()))
+			span.Finish(tracer.WithError(fmt.Errorf("panic: %v", recovered)), tracer.NoDebugStack())
+			observeTrace(name, opts, start, true)
+			panic(recovered)
+		}
+
+		span.Finish(tracer.WithError(err))
+		observeTrace(name, opts, start, err != nil)
+	}()
+
+	return fn(spanContext)
+}
+
+// observeTrace reports a span of Trace or TraceValue to the span metrics, with the resource and service set by
+// the span options. Like the tracer, the name of the span is the resource when no resource is set.
+func observeTrace(name string, opts []tracer.StartSpanOption, start time.Time, isError bool) {
+	if activeSpanMetrics.Load() == nil {
+		return
+	}
+
+	cfg := tracer.NewStartSpanConfig(opts...)
+	resource, _ := cfg.Tags[ext.ResourceName].(string)
+	service, _ := cfg.Tags[ext.ServiceName].(string)
+	observeSpan(name, service, cmp.Or(resource, name), start, isError)
+}
@@ -0,0 +1,138 @@
+package apm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/internal/openspans"
+)
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name          string
+		fnErr         error
+		expectedError string
+	}{
+		{
+			name:  "successful function",
+			fnErr: nil,
+		},
+		{
+			name:          "failing function",
+			fnErr:         errors.New("function failed"),
+			expectedError: "function failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			var fnCtx context.Context
+			err := Trace(context.Background(), "test.trace", func(ctx context.Context) error {
+				fnCtx = ctx
+				return tt.fnErr
+			}, tracer.ResourceName("test-resource"), tracer.SpanType(ext.SpanTypeWeb), tracer.Tag("order.id", 42))
+
+			if err != tt.fnErr {
+				t.Errorf("Error incorrect, expected '%v' got '%v'", tt.fnErr, err)
+			}
+
+			if _, ok := tracer.SpanFromContext(fnCtx); !ok {
+				t.Error("expected the function context to contain the span")
+			}
+			if openSpans := openspans.FromContext(fnCtx); len(openSpans) != 1 {
+				t.Errorf("expected the function context to track 1 open span, got %d", len(openSpans))
+			}
+
+			spans := mt.FinishedSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+
+			span := spans[0]
+			if span.OperationName() != "test.trace" {
+				t.Errorf("Operation name incorrect, expected 'test.trace' got '%s'", span.OperationName())
+			}
+			if span.Tag(ext.ResourceName) != "test-resource" {
+				t.Errorf("Resource name incorrect, expected 'test-resource' got '%v'", span.Tag(ext.ResourceName))
+			}
+			if span.Tag(ext.SpanType) != ext.SpanTypeWeb {
+				t.Errorf("Span type incorrect, expected '%s' got '%v'", ext.SpanTypeWeb, span.Tag(ext.SpanType))
+			}
+			if span.Tag("order.id") != float64(42) {
+				t.Errorf("Tag order.id incorrect, expected '42' got '%v'", span.Tag("order.id"))
+			}
+
+			if tt.expectedError == "" {
+				if span.Tag(ext.ErrorMsg) != nil {
+					t.Errorf("expected no error on span, got '%v'", span.Tag(ext.ErrorMsg))
+				}
+			} else if span.Tag(ext.ErrorMsg) != tt.expectedError {
+				t.Errorf("Span error incorrect, expected '%s' got '%v'", tt.expectedError, span.Tag(ext.ErrorMsg))
+			}
+		})
+	}
+}
+
+func TestTraceValue(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	value, err := TraceValue(context.Background(), "test.trace.value", func(ctx context.Context) (string, error) {
+		return "result", nil
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value != "result" {
+		t.Errorf("Value incorrect, expected 'result' got '%s'", value)
+	}
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].OperationName() != "test.trace.value" {
+		t.Errorf("Operation name incorrect, expected 'test.trace.value' got '%s'", spans[0].OperationName())
+	}
+}
+
+func TestTracePanic(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	var panicked interface{}
+	func() {
+		defer func() {
+			panicked = recover()
+		}()
+		_ = Trace(context.Background(), "test.trace.panic", func(ctx context.Context) error {
+			panic("something went wrong")
+		})
+	}()
+
+	if panicked != "something went wrong" {
+		t.Fatalf("expected the panic to be re-panicked, got '%v'", panicked)
+	}
+
+	spans := mt.FinishedSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Tag(ext.ErrorMsg) != "panic: something went wrong" {
+		t.Errorf("Span error incorrect, expected 'panic: something went wrong' got '%v'", span.Tag(ext.ErrorMsg))
+	}
+	if stack, _ := span.Tag(ext.ErrorStack).(string); !strings.Contains(stack, "TestTracePanic") {
+		t.Errorf("expected span error stack to contain the panic location, got '%s'", stack)
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	IntegrationChi   = "chi"
+	IntegrationHttp  = "http"
+	IntegrationSQL   = "sql"
+	IntegrationSQLX  = "sqlx"
+	IntegrationGorm  = "gorm"
+	IntegrationGRPC  = "grpc"
+	IntegrationRedis = "redis"
+	IntegrationPgx   = "pgx"
+	IntegrationGCP   = "gcp"
+	DefaultAgentPort = 8126
+)
+
+// Integrations lists the names of all integrations that can be enabled.
+var Integrations = []string{IntegrationChi, IntegrationHttp, IntegrationSQL, IntegrationSQLX, IntegrationGorm, IntegrationGRPC, IntegrationRedis, IntegrationPgx, IntegrationGCP}
+
+// Config holds the settings shared by the apm and logger packages.
+type Config struct {
+	Service   string
+	Env       string
+	Version   string
+	AgentHost string
+	AgentPort int
+	// SampleRate is the trace sample rate between 0 and 1, nil leaves sampling to the agent.
+	SampleRate  *float64
+	LogLevel    zapcore.Level
+	LogEncoding string
+	OutputPaths []string
+	// EnabledIntegrations lists the enabled integrations, nil enables all of them.
+	EnabledIntegrations []string
+}
+
+// FromEnv creates a Config from the following environment variables:
+//
+//	DD_SERVICE, DD_ENV, DD_VERSION, DD_AGENT_HOST, DD_TRACE_AGENT_PORT, DD_TRACE_SAMPLE_RATE,
+//	LOG_LEVEL, LOG_ENCODING, LOG_OUTPUT_PATHS and APM_INTEGRATIONS.
+//
+// List values are comma separated. All invalid values are reported in the returned error.
+func FromEnv() (Config, error) {
+	config := Config{
+		Service:     os.Getenv("DD_SERVICE"),
+		Env:         os.Getenv("DD_ENV"),
+		Version:     os.Getenv("DD_VERSION"),
+		AgentHost:   os.Getenv("DD_AGENT_HOST"),
+		AgentPort:   DefaultAgentPort,
+		LogLevel:    zapcore.InfoLevel,
+		LogEncoding: "json",
+		OutputPaths: []string{"stdout"},
+	}
+
+	var errs []error
+
+	if value := os.Getenv("DD_TRACE_AGENT_PORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid DD_TRACE_AGENT_PORT '%s': %w", value, err))
+		}
+		config.AgentPort = port
+	}
+
+	if value := os.Getenv("DD_TRACE_SAMPLE_RATE"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid DD_TRACE_SAMPLE_RATE '%s': %w", value, err))
+		} else {
+			config.SampleRate = &rate
+		}
+	}
+
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		level, err := ParseLogLevel(value)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		config.LogLevel = level
+	}
+
+	if value := os.Getenv("LOG_ENCODING"); value != "" {
+		config.LogEncoding = strings.ToLower(value)
+	}
+
+	if value := os.Getenv("LOG_OUTPUT_PATHS"); value != "" {
+		config.OutputPaths = splitList(value)
+	}
+
+	if value, ok := os.LookupEnv("APM_INTEGRATIONS"); ok {
+		config.EnabledIntegrations = splitList(value)
+	}
+
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return config, errors.Join(errs...)
+}
+
+// Validate checks the values of the Config and reports all invalid values in the returned error.
+func (config Config) Validate() error {
+	var errs []error
+
+	if config.AgentPort < 0 || config.AgentPort > 65535 {
+		errs = append(errs, fmt.Errorf("invalid agent port %d, expected a value between 0 and 65535", config.AgentPort))
+	}
+
+	if config.SampleRate != nil && (*config.SampleRate < 0 || *config.SampleRate > 1) {
+		errs = append(errs, fmt.Errorf("invalid sample rate %v, expected a value between 0 and 1", *config.SampleRate))
+	}
+
+	if config.LogEncoding != "" && config.LogEncoding != "json" && config.LogEncoding != "console" {
+		errs = append(errs, fmt.Errorf("invalid log encoding '%s', expected 'json' or 'console'", config.LogEncoding))
+	}
+
+	for _, integration := range config.EnabledIntegrations {
+		if !slices.Contains(Integrations, integration) {
+			errs = append(errs, fmt.Errorf("unknown integration '%s', expected one of %s", integration, strings.Join(Integrations, ", ")))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// AgentAddr returns the host:port of the Datadog agent, or an empty string when no agent host is configured.
+func (config Config) AgentAddr() string {
+	if config.AgentHost == "" {
+		return ""
+	}
+
+	port := config.AgentPort
+	if port == 0 {
+		port = DefaultAgentPort
+	}
+
+	return fmt.Sprintf("%s:%d", config.AgentHost, port)
+}
+
+// IntegrationEnabled reports whether the integration with the given name is enabled.
+func (config Config) IntegrationEnabled(name string) bool {
+	return config.EnabledIntegrations == nil || slices.Contains(config.EnabledIntegrations, name)
+}
+
+// ParseLogLevel converts the LOG_LEVEL values debug, info, warning, error and fatal to a zap level.
+func ParseLogLevel(value string) (zapcore.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warning":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	case "fatal":
+		return zapcore.FatalLevel, nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("invalid log level '%s', expected one of debug, info, warning, error or fatal", value)
+	}
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Run("defaults when no env vars", func(t *testing.T) {
+		for _, key := range []string{"DD_SERVICE", "DD_ENV", "DD_VERSION", "DD_AGENT_HOST", "DD_TRACE_AGENT_PORT", "DD_TRACE_SAMPLE_RATE", "LOG_LEVEL", "LOG_ENCODING", "LOG_OUTPUT_PATHS", "APM_INTEGRATIONS"} {
+			// Register the env var for restore after the test before clearing it
+			t.Setenv(key, "")
+			os.Unsetenv(key)
+		}
+
+		config, err := FromEnv()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if config.AgentPort != DefaultAgentPort {
+			t.Errorf("Agent port incorrect, expected '%d' got '%d'", DefaultAgentPort, config.AgentPort)
+		}
+		if config.SampleRate != nil {
+			t.Errorf("Expected sample rate to be nil, got '%v'", *config.SampleRate)
+		}
+		if config.LogLevel != zapcore.InfoLevel {
+			t.Errorf("Log level incorrect, expected '%s' got '%s'", zapcore.InfoLevel, config.LogLevel)
+		}
+		if config.LogEncoding != "json" {
+			t.Errorf("Log encoding incorrect, expected 'json' got '%s'", config.LogEncoding)
+		}
+		if !reflect.DeepEqual(config.OutputPaths, []string{"stdout"}) {
+			t.Errorf("Output paths incorrect, expected '[stdout]' got '%v'", config.OutputPaths)
+		}
+		if config.EnabledIntegrations != nil {
+			t.Errorf("Expected all integrations to be enabled, got '%v'", config.EnabledIntegrations)
+		}
+	})
+
+	t.Run("values from env vars", func(t *testing.T) {
+		t.Setenv("DD_SERVICE", "my-service")
+		t.Setenv("DD_ENV", "production")
+		t.Setenv("DD_VERSION", "1.0.0")
+		t.Setenv("DD_AGENT_HOST", "datadog-agent")
+		t.Setenv("DD_TRACE_AGENT_PORT", "9126")
+		t.Setenv("DD_TRACE_SAMPLE_RATE", "0.5")
+		t.Setenv("LOG_LEVEL", "warning")
+		t.Setenv("LOG_ENCODING", "console")
+		t.Setenv("LOG_OUTPUT_PATHS", "stdout, /tmp/app.log")
+		t.Setenv("APM_INTEGRATIONS", "chi,sql")
+
+		config, err := FromEnv()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		sampleRate := 0.5
+		expected := Config{
+			Service:             "my-service",
+			Env:                 "production",
+			Version:             "1.0.0",
+			AgentHost:           "datadog-agent",
+			AgentPort:           9126,
+			SampleRate:          &sampleRate,
+			LogLevel:            zapcore.WarnLevel,
+			LogEncoding:         "console",
+			OutputPaths:         []string{"stdout", "/tmp/app.log"},
+			EnabledIntegrations: []string{"chi", "sql"},
+		}
+		if !reflect.DeepEqual(config, expected) {
+			t.Errorf("Config incorrect, expected '%+v' got '%+v'", expected, config)
+		}
+		if config.AgentAddr() != "datadog-agent:9126" {
+			t.Errorf("Agent address incorrect, expected 'datadog-agent:9126' got '%s'", config.AgentAddr())
+		}
+	})
+
+	t.Run("invalid env vars", func(t *testing.T) {
+		t.Setenv("DD_TRACE_AGENT_PORT", "port")
+		t.Setenv("DD_TRACE_SAMPLE_RATE", "2")
+		t.Setenv("LOG_LEVEL", "invalid")
+		t.Setenv("LOG_ENCODING", "xml")
+		t.Setenv("APM_INTEGRATIONS", "unknown")
+
+		_, err := FromEnv()
+		if err == nil {
+			t.Fatal("Expected an error for the invalid env vars")
+		}
+
+		for _, expected := range []string{
+			"invalid DD_TRACE_AGENT_PORT 'port'",
+			"invalid sample rate 2",
+			"invalid log level 'invalid'",
+			"invalid log encoding 'xml'",
+			"unknown integration 'unknown'",
+		} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("Expected error to contain '%s', got '%s'", expected, err.Error())
+			}
+		}
+	})
+}
+
+func TestIntegrationEnabled(t *testing.T) {
+	tests := []struct {
+		name         string
+		integrations []string
+		expected     bool
+	}{
+		{
+			name:         "all integrations enabled by default",
+			integrations: nil,
+			expected:     true,
+		},
+		{
+			name:         "integration in list",
+			integrations: []string{IntegrationSQL, IntegrationChi},
+			expected:     true,
+		},
+		{
+			name:         "integration not in list",
+			integrations: []string{IntegrationSQL},
+			expected:     false,
+		},
+		{
+			name:         "all integrations disabled",
+			integrations: []string{},
+			expected:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{EnabledIntegrations: tt.integrations}
+
+			if config.IntegrationEnabled(IntegrationChi) != tt.expected {
+				t.Errorf("Expected IntegrationEnabled to be %v", tt.expected)
+			}
+		})
+	}
+}
+
+func TestAgentAddr(t *testing.T) {
+	if addr := (Config{}).AgentAddr(); addr != "" {
+		t.Errorf("Expected empty agent address without agent host, got '%s'", addr)
+	}
+
+	if addr := (Config{AgentHost: "localhost"}).AgentAddr(); addr != "localhost:8126" {
+		t.Errorf("Agent address incorrect, expected 'localhost:8126' got '%s'", addr)
+	}
+}
@@ -0,0 +1,25 @@
+// Package openspans tracks the spans started by the apm helpers in the context, so a fatal log can finish every
+// span of the trace that is still open. The tracer only sends a trace once all of its spans are finished.
+package openspans
+
+import (
+	"context"
+	"slices"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+)
+
+type openSpansKey struct{}
+
+// ContextWithSpan returns a context that tracks the span along with the spans tracked by ctx.
+func ContextWithSpan(ctx context.Context, span *tracer.Span) context.Context {
+	spans, _ := ctx.Value(openSpansKey{}).([]*tracer.Span)
+	return context.WithValue(ctx, openSpansKey{}, append(slices.Clip(spans), span))
+}
+
+// FromContext returns the spans tracked by the context, from the outermost to the innermost span.
+// Spans that are finished already are returned as well, finishing them again has no effect.
+func FromContext(ctx context.Context) []*tracer.Span {
+	spans, _ := ctx.Value(openSpansKey{}).([]*tracer.Span)
+	return spans
+}
@@ -0,0 +1,42 @@
+package openspans
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+)
+
+func TestContextWithSpan(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	if spans := FromContext(context.Background()); len(spans) != 0 {
+		t.Errorf("expected no spans without tracked spans, got %d", len(spans))
+	}
+
+	handler := tracer.StartSpan("http.request")
+	handlerContext := ContextWithSpan(context.Background(), handler)
+	service := tracer.StartSpan("orders.process")
+	serviceContext := ContextWithSpan(handlerContext, service)
+	repository := tracer.StartSpan("orders.get")
+	repositoryContext := ContextWithSpan(serviceContext, repository)
+
+	// a sibling does not change the spans tracked by its parent context
+	ContextWithSpan(serviceContext, tracer.StartSpan("orders.notify"))
+
+	spans := FromContext(repositoryContext)
+	expected := []*tracer.Span{handler, service, repository}
+	if len(spans) != len(expected) {
+		t.Fatalf("expected %d spans, got %d", len(expected), len(spans))
+	}
+	for i, span := range spans {
+		if span != expected[i] {
+			t.Errorf("Span %d incorrect, expected '%v' got '%v'", i, expected[i], span)
+		}
+	}
+	if spans := FromContext(serviceContext); len(spans) != 2 {
+		t.Errorf("expected 2 spans in the context of the service, got %d", len(spans))
+	}
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"go.uber.org/zap"
+)
+
+// TraceIDFormat selects the trace correlation fields added to log lines, formats can be combined with |.
+type TraceIDFormat int
+
+const (
+	// TraceIDDatadog adds the lower 64 bits of the trace ID as decimal dd.trace_id and the span ID as decimal dd.span_id,
+	// both as strings so JSON consumers do not lose precision above 2^53.
+	TraceIDDatadog TraceIDFormat = 1 << iota
+	// TraceIDHex128 adds the full 128-bit trace ID as 32 hex characters in dd.trace_id_128.
+	TraceIDHex128
+	// TraceIDW3C adds the W3C trace-id and parent-id as hex trace_id and span_id, for OpenTelemetry consumers.
+	TraceIDW3C
+
+	// DefaultTraceIDFormat is used when no format is set with WithTraceIDFormat.
+	DefaultTraceIDFormat = TraceIDDatadog | TraceIDHex128
+)
+
+// WithTraceIDFormat sets the trace correlation fields added to log lines.
+// Example:
+//
+//	logger := NewLogger(WithTraceIDFormat(TraceIDDatadog | TraceIDW3C))
+func WithTraceIDFormat(format TraceIDFormat) LoggerOption {
+	return func(l *Logger) {
+		l.traceIDFormat = format
+	}
+}
+
+func (log Logger) traceFields(span *tracer.Span) []interface{} {
+	spanContext := span.Context()
+
+	format := log.traceIDFormat
+	if format == 0 {
+		format = DefaultTraceIDFormat
+	}
+
+	var fields []interface{}
+	if format&TraceIDDatadog != 0 {
+		fields = append(fields, "dd.trace_id", strconv.FormatUint(spanContext.TraceIDLower(), 10), "dd.span_id", strconv.FormatUint(spanContext.SpanID(), 10))
+	}
+	if format&TraceIDHex128 != 0 {
+		fields = append(fields, "dd.trace_id_128", spanContext.TraceID())
+	}
+	if format&TraceIDW3C != 0 {
+		fields = append(fields, "trace_id", spanContext.TraceID(), "span_id", fmt.Sprintf("%016x", spanContext.SpanID()))
+	}
+	return fields
+}
+
+func (log Logger) withTraceFields(ctx context.Context, keysAndValues []interface{}) []interface{} {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return keysAndValues
+	}
+	return append(log.traceFields(span), keysAndValues...)
+}
+
+func (log Logger) withTraceZapFields(ctx context.Context, fields []zap.Field) []zap.Field {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return fields
+	}
+
+	traceFields := log.traceFields(span)
+	zapFields := make([]zap.Field, 0, len(traceFields)/2+len(fields))
+	for i := 0; i < len(traceFields); i += 2 {
+		zapFields = append(zapFields, zap.Any(traceFields[i].(string), traceFields[i+1]))
+	}
+	return append(zapFields, fields...)
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"go.uber.org/zap"
+)
+
+func TestTraceIDFormat(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+	defer span.Finish()
+
+	traceID128 := span.Context().TraceID()
+	traceIDLower := strconv.FormatUint(span.Context().TraceIDLower(), 10)
+	spanID := span.Context().SpanID()
+	spanIDDecimal := strconv.FormatUint(spanID, 10)
+
+	if len(traceID128) != 32 {
+		t.Fatalf("expected the mocktracer to generate a 128-bit trace ID, got '%s'", traceID128)
+	}
+
+	tests := []struct {
+		name           string
+		option         []LoggerOption
+		expectedFields map[string]interface{}
+	}{
+		{
+			name: "default format",
+			expectedFields: map[string]interface{}{
+				"dd.trace_id":     traceIDLower,
+				"dd.span_id":      spanIDDecimal,
+				"dd.trace_id_128": traceID128,
+			},
+		},
+		{
+			name:   "datadog format",
+			option: []LoggerOption{WithTraceIDFormat(TraceIDDatadog)},
+			expectedFields: map[string]interface{}{
+				"dd.trace_id": traceIDLower,
+				"dd.span_id":  spanIDDecimal,
+			},
+		},
+		{
+			name:   "w3c format",
+			option: []LoggerOption{WithTraceIDFormat(TraceIDW3C)},
+			expectedFields: map[string]interface{}{
+				"trace_id": traceID128,
+				"span_id":  fmt.Sprintf("%016x", spanID),
+			},
+		},
+		{
+			name:   "combined formats",
+			option: []LoggerOption{WithTraceIDFormat(TraceIDDatadog | TraceIDHex128 | TraceIDW3C)},
+			expectedFields: map[string]interface{}{
+				"dd.trace_id":     traceIDLower,
+				"dd.span_id":      spanIDDecimal,
+				"dd.trace_id_128": traceID128,
+				"trace_id":        traceID128,
+				"span_id":         fmt.Sprintf("%016x", spanID),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DD_SERVICE", "")
+			t.Setenv("DD_ENV", "")
+			t.Setenv("DD_VERSION", "")
+
+			captureLog, logs := setupLogsCapture()
+			options := append(tt.option, func(l *Logger) {
+				l.internalLogger = captureLog
+			})
+			logger := NewLogger(options...)
+
+			logger.Info(spanContext, "printf message")
+			logger.Infow(spanContext, "structured message")
+			logger.InfoFields(spanContext, "typed message", zap.Skip())
+			NewSlogLogger(logger).InfoContext(spanContext, "slog message")
+
+			logEntries := logs.All()
+			if len(logEntries) != 4 {
+				t.Fatalf("expected 4 logs, got %d", len(logEntries))
+			}
+
+			for _, logEntry := range logEntries {
+				if fields := logEntry.ContextMap(); !reflect.DeepEqual(fields, tt.expectedFields) {
+					t.Errorf("Fields of '%s' incorrect, expected '%v' got '%v'", logEntry.Message, tt.expectedFields, fields)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+)
+
+// ErrorClass determines how an error is recorded on a span.
+type ErrorClass int
+
+const (
+	// ErrorUnhandled records the error and marks the span as errored.
+	ErrorUnhandled ErrorClass = iota
+	// ErrorHandled records the error, but does not mark the span as errored.
+	ErrorHandled
+	// ErrorIgnored does not record the error at all.
+	ErrorIgnored
+)
+
+const (
+	errorChainTag   = "error.chain"
+	errorHandledTag = "error.handled"
+
+	errorKindAttribute    = "error.kind"
+	errorMessageAttribute = "error.message"
+	errorStackAttribute   = "error.stack"
+)
+
+// ErrorClassifier returns the ErrorClass of an error.
+type ErrorClassifier func(err error) ErrorClass
+
+// IgnoreErrors returns a classifier that ignores errors matching one of the targets according to errors.Is.
+// Example:
+//
+//	logger := NewLogger(WithErrorClassifier(IgnoreErrors(context.Canceled)))
+func IgnoreErrors(targets ...error) ErrorClassifier {
+	return classifyErrors(ErrorIgnored, targets)
+}
+
+// HandleErrors returns a classifier that marks errors matching one of the targets according to errors.Is as handled.
+// Example:
+//
+//	logger := NewLogger(WithErrorClassifier(HandleErrors(sql.ErrNoRows)))
+func HandleErrors(targets ...error) ErrorClassifier {
+	return classifyErrors(ErrorHandled, targets)
+}
+
+func classifyErrors(class ErrorClass, targets []error) ErrorClassifier {
+	return func(err error) ErrorClass {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return class
+			}
+		}
+		return ErrorUnhandled
+	}
+}
+
+// WithErrorClassifier sets the classifiers used when recording errors on spans.
+// The first classifier that does not return ErrorUnhandled determines the class of an error.
+func WithErrorClassifier(classifiers ...ErrorClassifier) LoggerOption {
+	return func(l *Logger) {
+		l.errorClassifiers = append(l.errorClassifiers, classifiers...)
+	}
+}
+
+// RecordError records the error on the span in the context, without logging it.
+// It sets error.message, error.type, error.stack and the unwrapped error.chain on the span,
+// and marks the span as errored unless a classifier marks the error as handled or ignored.
+func (log Logger) RecordError(ctx context.Context, err error) {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok || err == nil {
+		return
+	}
+
+	log.recordSpanError(span, err, errorStack(err, 2))
+}
+
+func (log Logger) classifyError(err error) ErrorClass {
+	for _, classifier := range log.errorClassifiers {
+		if class := classifier(err); class != ErrorUnhandled {
+			return class
+		}
+	}
+	return ErrorUnhandled
+}
+
+// ErrorErr logs the message with the error in the Datadog reserved error.kind, error.message and error.stack
+// attributes, and records the error on the span with the same values, so the log and trace error views match.
+// The stack of the error is used when it carries one, otherwise the stack of the caller.
+// Example:
+//
+//	logger.ErrorErr(ctx, err, "Failed to create order", "order_id", order.ID)
+func (log Logger) ErrorErr(ctx context.Context, err error, msg string, keysAndValues ...interface{}) {
+	if err == nil {
+		err = errors.New(msg)
+	}
+	stack := errorStack(err, 2)
+
+	span, ok := tracer.SpanFromContext(ctx)
+	if ok {
+		log.recordSpanError(span, err, stack)
+	}
+
+	keysAndValues = append(slices.Clip(keysAndValues),
+		errorKindAttribute, reflect.TypeOf(err).String(),
+		errorMessageAttribute, err.Error(),
+		errorStackAttribute, stack,
+	)
+	log.internalLogger.Errorw(msg, log.withTraceFields(ctx, keysAndValues)...)
+}
+
+func (log Logger) recordSpanError(span *tracer.Span, err error, stack string) {
+	switch log.classifyError(err) {
+	case ErrorIgnored:
+		return
+	case ErrorHandled:
+		span.SetTag(ext.ErrorMsg, err.Error())
+		span.SetTag(ext.ErrorType, reflect.TypeOf(err).String())
+		span.SetTag(errorHandledTag, true)
+	default:
+		span.SetTag(ext.ErrorNoStackTrace, err)
+	}
+
+	span.SetTag(ext.ErrorStack, stack)
+	if chain := errorChain(err); len(chain) > 1 {
+		span.SetTag(errorChainTag, chain)
+	}
+}
+
+// errorChain returns the type and message of the error and all errors it wraps, depth first.
+func errorChain(err error) []string {
+	var chain []string
+
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		chain = append(chain, fmt.Sprintf("%T: %s", err, err.Error()))
+
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(wrapped.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, err := range wrapped.Unwrap() {
+				walk(err)
+			}
+		}
+	}
+	walk(err)
+
+	return chain
+}
+
+// errorStack returns the stack trace carried by the error, like the errors of github.com/pkg/errors which print
+// their stack with %+v, or the stack of the caller, skipping the given number of frames.
+func errorStack(err error, skip int) string {
+	var formatter fmt.Formatter
+	if errors.As(err, &formatter) {
+		if stack := fmt.Sprintf("%+v", formatter); stack != fmt.Sprintf("%v", formatter) {
+			return stack
+		}
+	}
+	return callerStack(skip + 1)
+}
+
+// callerStack formats the stack of the caller, skipping the given number of frames.
+func callerStack(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&stack, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+
+	return stack.String()
+}
@@ -0,0 +1,296 @@
+package logger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+)
+
+func TestRecordError(t *testing.T) {
+	errDatabase := errors.New("database unavailable")
+
+	tests := []struct {
+		name            string
+		err             error
+		expectedMessage interface{}
+		expectedType    interface{}
+		expectedError   bool
+		expectedHandled interface{}
+		expectedChain   []string
+	}{
+		{
+			name:            "unhandled error",
+			err:             errDatabase,
+			expectedMessage: "database unavailable",
+			expectedType:    "*errors.errorString",
+			expectedError:   true,
+		},
+		{
+			name:            "wrapped error",
+			err:             fmt.Errorf("get order: %w", errDatabase),
+			expectedMessage: "get order: database unavailable",
+			expectedType:    "*fmt.wrapError",
+			expectedError:   true,
+			expectedChain: []string{
+				"*fmt.wrapError: get order: database unavailable",
+				"*errors.errorString: database unavailable",
+			},
+		},
+		{
+			name:            "joined errors",
+			err:             errors.Join(errDatabase, sql.ErrConnDone),
+			expectedMessage: "database unavailable\nsql: connection is already closed",
+			expectedType:    "*errors.joinError",
+			expectedError:   true,
+			expectedChain: []string{
+				"*errors.joinError: database unavailable\nsql: connection is already closed",
+				"*errors.errorString: database unavailable",
+				"*errors.errorString: sql: connection is already closed",
+			},
+		},
+		{
+			name:            "handled error",
+			err:             fmt.Errorf("get order: %w", sql.ErrNoRows),
+			expectedMessage: "get order: sql: no rows in result set",
+			expectedType:    "*fmt.wrapError",
+			expectedError:   false,
+			expectedHandled: "true",
+			expectedChain: []string{
+				"*fmt.wrapError: get order: sql: no rows in result set",
+				"*errors.errorString: sql: no rows in result set",
+			},
+		},
+		{
+			name:            "ignored error",
+			err:             context.Canceled,
+			expectedMessage: nil,
+			expectedType:    nil,
+			expectedError:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			logger := NewLogger(WithErrorClassifier(IgnoreErrors(context.Canceled), HandleErrors(sql.ErrNoRows)))
+
+			span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+			logger.RecordError(spanContext, tt.err)
+			span.Finish()
+
+			spans := mt.FinishedSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			finishedSpan := spans[0]
+
+			if finishedSpan.Tag(ext.ErrorMsg) != tt.expectedMessage {
+				t.Errorf("Error message incorrect, expected '%v' got '%v'", tt.expectedMessage, finishedSpan.Tag(ext.ErrorMsg))
+			}
+			if finishedSpan.Tag(ext.ErrorType) != tt.expectedType {
+				t.Errorf("Error type incorrect, expected '%v' got '%v'", tt.expectedType, finishedSpan.Tag(ext.ErrorType))
+			}
+			if isErrored := finishedSpan.Tag(ext.MapSpanError) == int32(1); isErrored != tt.expectedError {
+				t.Errorf("Span error flag incorrect, expected '%v' got '%v'", tt.expectedError, isErrored)
+			}
+			if finishedSpan.Tag(errorHandledTag) != tt.expectedHandled {
+				t.Errorf("Error handled incorrect, expected '%v' got '%v'", tt.expectedHandled, finishedSpan.Tag(errorHandledTag))
+			}
+
+			if tt.expectedMessage != nil {
+				if stack, _ := finishedSpan.Tag(ext.ErrorStack).(string); !strings.Contains(stack, "TestRecordError") {
+					t.Errorf("expected error stack to contain the caller, got '%s'", stack)
+				}
+			}
+
+			for i, expected := range tt.expectedChain {
+				key := fmt.Sprintf("%s.%d", errorChainTag, i)
+				if finishedSpan.Tag(key) != expected {
+					t.Errorf("Error chain %s incorrect, expected '%s' got '%v'", key, expected, finishedSpan.Tag(key))
+				}
+			}
+		})
+	}
+}
+
+func TestRecordErrorWithoutSpan(t *testing.T) {
+	logger := NewLogger()
+
+	// Should not panic
+	logger.RecordError(context.Background(), errors.New("no span"))
+}
+
+func TestErrorLogClassification(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	captureLog, logs := setupLogsCapture()
+	logger := Logger{
+		internalLogger: captureLog,
+		errorClassifiers: []ErrorClassifier{func(err error) ErrorClass {
+			if strings.HasPrefix(err.Error(), "order not found") {
+				return ErrorHandled
+			}
+			return ErrorUnhandled
+		}},
+	}
+
+	span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+	logger.Error(spanContext, "order not found: %s", sql.ErrNoRows)
+	span.Finish()
+
+	if len(logs.All()) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(logs.All()))
+	}
+
+	finishedSpan := mt.FinishedSpans()[0]
+	if finishedSpan.Tag(ext.MapSpanError) == int32(1) {
+		t.Error("expected handled error not to mark the span as errored")
+	}
+	if finishedSpan.Tag(ext.ErrorMsg) != "order not found: sql: no rows in result set" {
+		t.Errorf("Error message incorrect, got '%v'", finishedSpan.Tag(ext.ErrorMsg))
+	}
+}
+
+// stackError is an error that carries its own stack trace, printed with %+v like the errors of github.com/pkg/errors.
+type stackError struct {
+	msg   string
+	stack string
+}
+
+func (e stackError) Error() string {
+	return e.msg
+}
+
+func (e stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\n%s", e.msg, e.stack)
+		return
+	}
+	fmt.Fprint(s, e.msg)
+}
+
+func TestErrorErr(t *testing.T) {
+	errStack := stackError{msg: "payment declined", stack: "main.charge\n\t/app/payment.go:42"}
+
+	tests := []struct {
+		name            string
+		err             error
+		expectedKind    string
+		expectedMessage string
+		expectedStack   string
+	}{
+		{
+			name:            "error without stack",
+			err:             errors.New("database unavailable"),
+			expectedKind:    "*errors.errorString",
+			expectedMessage: "database unavailable",
+			expectedStack:   "TestErrorErr",
+		},
+		{
+			name:            "error with stack",
+			err:             errStack,
+			expectedKind:    "logger.stackError",
+			expectedMessage: "payment declined",
+			expectedStack:   "/app/payment.go:42",
+		},
+		{
+			name:            "wrapped error with stack",
+			err:             fmt.Errorf("create order: %w", errStack),
+			expectedKind:    "*fmt.wrapError",
+			expectedMessage: "create order: payment declined",
+			expectedStack:   "/app/payment.go:42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			captureLog, logs := setupLogsCapture()
+			logger := Logger{internalLogger: captureLog}
+
+			span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+			logger.ErrorErr(spanContext, tt.err, "Failed to create order", "order_id", "order-1")
+			span.Finish()
+
+			if len(logs.All()) != 1 {
+				t.Fatalf("expected 1 log, got %d", len(logs.All()))
+			}
+			logEntry := logs.All()[0]
+			if logEntry.Message != "Failed to create order" {
+				t.Errorf("Message incorrect, expected 'Failed to create order' got '%s'", logEntry.Message)
+			}
+
+			fields := logEntry.ContextMap()
+			if fields["order_id"] != "order-1" {
+				t.Errorf("Message order_id incorrect, expected 'order-1' got '%v'", fields["order_id"])
+			}
+			if fields[errorKindAttribute] != tt.expectedKind {
+				t.Errorf("Message error.kind incorrect, expected '%s' got '%v'", tt.expectedKind, fields[errorKindAttribute])
+			}
+			if fields[errorMessageAttribute] != tt.expectedMessage {
+				t.Errorf("Message error.message incorrect, expected '%s' got '%v'", tt.expectedMessage, fields[errorMessageAttribute])
+			}
+			stack, _ := fields[errorStackAttribute].(string)
+			if !strings.Contains(stack, tt.expectedStack) {
+				t.Errorf("Message error.stack incorrect, expected it to contain '%s' got '%s'", tt.expectedStack, stack)
+			}
+
+			finishedSpan := mt.FinishedSpans()[0]
+			if finishedSpan.Tag(ext.ErrorType) != tt.expectedKind {
+				t.Errorf("Error type incorrect, expected '%s' got '%v'", tt.expectedKind, finishedSpan.Tag(ext.ErrorType))
+			}
+			if finishedSpan.Tag(ext.ErrorMsg) != tt.expectedMessage {
+				t.Errorf("Error message incorrect, expected '%s' got '%v'", tt.expectedMessage, finishedSpan.Tag(ext.ErrorMsg))
+			}
+			if finishedSpan.Tag(ext.ErrorStack) != stack {
+				t.Errorf("Error stack incorrect, expected '%s' got '%v'", stack, finishedSpan.Tag(ext.ErrorStack))
+			}
+			if finishedSpan.Tag(ext.MapSpanError) != int32(1) {
+				t.Error("expected the span to be marked as errored")
+			}
+		})
+	}
+}
+
+func TestErrorErrWithoutSpan(t *testing.T) {
+	captureLog, logs := setupLogsCapture()
+	logger := Logger{internalLogger: captureLog}
+
+	logger.ErrorErr(context.Background(), nil, "Failed to create order")
+
+	if len(logs.All()) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(logs.All()))
+	}
+	fields := logs.All()[0].ContextMap()
+	if fields[errorMessageAttribute] != "Failed to create order" {
+		t.Errorf("Message error.message incorrect, expected 'Failed to create order' got '%v'", fields[errorMessageAttribute])
+	}
+	if _, ok := fields["dd.trace_id"]; ok {
+		t.Error("expected no dd.trace_id without span in context")
+	}
+}
+
+func TestErrorErrKeepsCallerValues(t *testing.T) {
+	captureLog, _ := setupLogsCapture()
+	logger := Logger{internalLogger: captureLog}
+
+	backing := []interface{}{"order_id", 1, "unused", "value"}
+	keysAndValues := backing[:2]
+	logger.ErrorErr(context.Background(), errors.New("payment declined"), "Failed to create order", keysAndValues...)
+
+	if backing[2] != "unused" || backing[3] != "value" {
+		t.Errorf("Caller values incorrect, expected '[unused value]' got '%v'", backing[2:])
+	}
+}
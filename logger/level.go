@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Level returns the minimum enabled log level.
+func (log Logger) Level() zapcore.Level {
+	return log.internalLogger.Level()
+}
+
+// SetLevel changes the minimum enabled log level of the logger and all loggers derived from it.
+// It has no effect when the logger was not created by NewLogger.
+func (log Logger) SetLevel(level zapcore.Level) {
+	if log.level == nil {
+		return
+	}
+	log.level.SetLevel(level)
+}
+
+// LevelHandler returns an http.Handler that returns the log level on a GET request,
+// and changes it on a PUT request with a body like {"level":"debug"}.
+// Example:
+//
+//	router.Handle("/log/level", logger.LevelHandler())
+func (log Logger) LevelHandler() http.Handler {
+	if log.level == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "log level of this logger can not be changed", http.StatusNotImplemented)
+		})
+	}
+	return log.level
+}
+
+// watchLevelSignals increases the verbosity one level on every increase signal and decreases it on every
+// decrease signal. The level reverts to the level from before the first signal when no signal is received
+// within revertAfter, unless the level was changed in another way since, like with the LevelHandler.
+// It returns when the signals channel is closed.
+func (log Logger) watchLevelSignals(signals <-chan os.Signal, increase os.Signal, decrease os.Signal, revertAfter time.Duration) {
+	var revertLevel, signalLevel zapcore.Level
+	pending := false
+	revertLevelChange := func() {
+		if pending && log.Level() == signalLevel {
+			log.SetLevel(revertLevel)
+		}
+		pending = false
+	}
+
+	revert := time.NewTimer(revertAfter)
+	revert.Stop()
+	defer revert.Stop()
+
+	for {
+		select {
+		case signal, ok := <-signals:
+			if !ok {
+				revertLevelChange()
+				return
+			}
+
+			level := log.Level()
+			if !pending || level != signalLevel {
+				revertLevel = level
+			}
+			switch signal {
+			case increase:
+				level = max(level-1, zapcore.DebugLevel)
+			case decrease:
+				level = min(level+1, zapcore.FatalLevel)
+			}
+			log.SetLevel(level)
+			signalLevel = level
+			pending = true
+			revert.Reset(revertAfter)
+		case <-revert.C:
+			revertLevelChange()
+		}
+	}
+}
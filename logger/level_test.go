@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSetLevel(t *testing.T) {
+	logger := NewLogger(WithConfig(zap.Config{
+		Level:    zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		Encoding: "console",
+	}))
+	child := logger.Named("child")
+
+	logger.SetLevel(zapcore.DebugLevel)
+
+	if logger.Level() != zapcore.DebugLevel {
+		t.Errorf("Level incorrect, expected '%s' got '%s'", zapcore.DebugLevel, logger.Level())
+	}
+	if child.Level() != zapcore.DebugLevel {
+		t.Errorf("Child level incorrect, expected '%s' got '%s'", zapcore.DebugLevel, child.Level())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "info")
+	logger := NewLogger()
+	handler := logger.LevelHandler()
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	if strings.TrimSpace(response.Body.String()) != `{"level":"info"}` {
+		t.Errorf("Response incorrect, expected '{\"level\":\"info\"}' got '%s'", response.Body.String())
+	}
+
+	response = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"debug"}`))
+	request.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Status code incorrect, expected '%d' got '%d'", http.StatusOK, response.Code)
+	}
+	if logger.Level() != zapcore.DebugLevel {
+		t.Errorf("Level incorrect, expected '%s' got '%s'", zapcore.DebugLevel, logger.Level())
+	}
+
+	t.Run("logger without adjustable level", func(t *testing.T) {
+		captureLogger, _ := setupLogsCapture()
+		logger := Logger{internalLogger: captureLogger}
+
+		response := httptest.NewRecorder()
+		logger.LevelHandler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+		if response.Code != http.StatusNotImplemented {
+			t.Errorf("Status code incorrect, expected '%d' got '%d'", http.StatusNotImplemented, response.Code)
+		}
+	})
+}
+
+func TestWatchLevelSignals(t *testing.T) {
+	logger := NewLogger(WithConfig(zap.Config{
+		Level:    zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		Encoding: "console",
+	}))
+
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.watchLevelSignals(signals, os.Interrupt, os.Kill, 50*time.Millisecond)
+	}()
+
+	signals <- os.Interrupt
+	signals <- os.Interrupt
+	waitForLevel(t, logger, zapcore.DebugLevel)
+
+	signals <- os.Kill
+	waitForLevel(t, logger, zapcore.InfoLevel)
+	signals <- os.Kill
+	waitForLevel(t, logger, zapcore.WarnLevel)
+
+	// Reverts to the original level after the timeout
+	waitForLevel(t, logger, zapcore.InfoLevel)
+
+	signals <- os.Interrupt
+	waitForLevel(t, logger, zapcore.DebugLevel)
+	close(signals)
+	<-done
+
+	if logger.Level() != zapcore.InfoLevel {
+		t.Errorf("Expected level to revert to '%s' when stopped, got '%s'", zapcore.InfoLevel, logger.Level())
+	}
+}
+
+func TestWatchLevelSignalsExternalChange(t *testing.T) {
+	logger := NewLogger(WithConfig(zap.Config{
+		Level:    zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		Encoding: "console",
+	}))
+
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.watchLevelSignals(signals, os.Interrupt, os.Kill, 50*time.Millisecond)
+	}()
+
+	// The level changed after the start is the level reverted to
+	logger.SetLevel(zapcore.WarnLevel)
+	signals <- os.Interrupt
+	waitForLevel(t, logger, zapcore.InfoLevel)
+	waitForLevel(t, logger, zapcore.WarnLevel)
+
+	// A level changed after the signal is not reverted
+	signals <- os.Interrupt
+	waitForLevel(t, logger, zapcore.InfoLevel)
+	logger.SetLevel(zapcore.ErrorLevel)
+	time.Sleep(100 * time.Millisecond)
+	close(signals)
+	<-done
+
+	if logger.Level() != zapcore.ErrorLevel {
+		t.Errorf("Expected level '%s' changed after the signal to be kept, got '%s'", zapcore.ErrorLevel, logger.Level())
+	}
+}
+
+func waitForLevel(t *testing.T, logger Logger, expected zapcore.Level) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for logger.Level() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Level incorrect, expected '%s' got '%s'", expected, logger.Level())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -2,18 +2,27 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"strings"
+	"slices"
 
 	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/internal/openspans"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 type Logger struct {
-	name           string
-	internalLogger *zap.SugaredLogger
+	name             string
+	service          string
+	env              string
+	version          string
+	internalLogger   *zap.SugaredLogger
+	level            *zap.AtomicLevel
+	traceIDFormat    TraceIDFormat
+	errorClassifiers []ErrorClassifier
 }
 
 type LoggerOption func(*Logger)
@@ -26,6 +35,56 @@ func WithConfig(config zap.Config) LoggerOption {
 			panic(err)
 		}
 		l.internalLogger = zapLogger.Sugar()
+		l.level = &config.Level
+	}
+}
+
+// WithApmConfig configures the log level, encoding, output paths and service tags from the shared apm configuration.
+func WithApmConfig(cfg config.Config) LoggerOption {
+	encoding := cfg.LogEncoding
+	if encoding == "" {
+		encoding = "json"
+	}
+
+	outputPaths := cfg.OutputPaths
+	if len(outputPaths) == 0 {
+		outputPaths = []string{"stdout"}
+	}
+
+	withConfig := WithConfig(newZapConfig(cfg.LogLevel, encoding, outputPaths))
+
+	return func(l *Logger) {
+		withConfig(l)
+		if cfg.Service != "" {
+			l.service = cfg.Service
+		}
+		if cfg.Env != "" {
+			l.env = cfg.Env
+		}
+		if cfg.Version != "" {
+			l.version = cfg.Version
+		}
+	}
+}
+
+// WithService sets the dd.service added to every log line, overriding DD_SERVICE.
+func WithService(service string) LoggerOption {
+	return func(l *Logger) {
+		l.service = service
+	}
+}
+
+// WithEnv sets the dd.env added to every log line, overriding DD_ENV.
+func WithEnv(env string) LoggerOption {
+	return func(l *Logger) {
+		l.env = env
+	}
+}
+
+// WithVersion sets the dd.version added to every log line, overriding DD_VERSION.
+func WithVersion(version string) LoggerOption {
+	return func(l *Logger) {
+		l.version = version
 	}
 }
 
@@ -36,6 +95,8 @@ func WithName(name string) LoggerOption {
 }
 
 // NewLogger creates a new Logger instance with the provided options.
+// Every log line contains the dd.service, dd.env and dd.version from the options,
+// or from the DD_SERVICE, DD_ENV and DD_VERSION environment variables when not set.
 // Example:
 //
 //	logger := NewLogger(WithConfig(zap.Config{
@@ -50,37 +111,58 @@ func NewLogger(options ...LoggerOption) Logger {
 	}
 
 	if logger.internalLogger == nil {
-		logger.internalLogger = defaultLogger()
+		logger.internalLogger, logger.level = defaultLogger()
 	}
 
 	if logger.name != "" {
 		logger.internalLogger = logger.internalLogger.Named(logger.name)
 	}
 
+	if fields := logger.serviceFields(); len(fields) > 0 {
+		logger.internalLogger = logger.internalLogger.With(fields...)
+	}
+
 	return logger
 }
 
-func defaultLogger() *zap.SugaredLogger {
-	var logLevel zapcore.Level
-	switch strings.ToLower(os.Getenv("LOG_LEVEL")) {
-	case "debug":
-		logLevel = zapcore.DebugLevel
-	case "info":
-		logLevel = zapcore.InfoLevel
-	case "warning":
-		logLevel = zapcore.WarnLevel
-	case "error":
-		logLevel = zapcore.ErrorLevel
-	case "fatal":
-		logLevel = zapcore.FatalLevel
-	default:
-		logLevel = zapcore.InfoLevel
-	}
-
-	config := zap.Config{
+// serviceFields returns the Datadog unified service tags, falling back to the DD_* environment variables.
+func (log *Logger) serviceFields() []interface{} {
+	if log.service == "" {
+		log.service = os.Getenv("DD_SERVICE")
+	}
+	if log.env == "" {
+		log.env = os.Getenv("DD_ENV")
+	}
+	if log.version == "" {
+		log.version = os.Getenv("DD_VERSION")
+	}
+
+	var fields []interface{}
+	if log.service != "" {
+		fields = append(fields, "dd.service", log.service)
+	}
+	if log.env != "" {
+		fields = append(fields, "dd.env", log.env)
+	}
+	if log.version != "" {
+		fields = append(fields, "dd.version", log.version)
+	}
+	return fields
+}
+
+func defaultLogger() (*zap.SugaredLogger, *zap.AtomicLevel) {
+	logLevel, _ := config.ParseLogLevel(os.Getenv("LOG_LEVEL"))
+
+	zapConfig := newZapConfig(logLevel, "json", []string{"stdout"})
+	logger, _ := zapConfig.Build()
+	return logger.Sugar(), &zapConfig.Level
+}
+
+func newZapConfig(logLevel zapcore.Level, encoding string, outputPaths []string) zap.Config {
+	return zap.Config{
 		Level:       zap.NewAtomicLevelAt(logLevel),
 		Development: false,
-		Encoding:    "json",
+		Encoding:    encoding,
 		EncoderConfig: zapcore.EncoderConfig{
 			TimeKey:        "time",
 			LevelKey:       "status",
@@ -93,18 +175,15 @@ func defaultLogger() *zap.SugaredLogger {
 			EncodeDuration: zapcore.SecondsDurationEncoder,
 			EncodeCaller:   zapcore.ShortCallerEncoder,
 		},
-		OutputPaths:      []string{"stdout"},
+		OutputPaths:      outputPaths,
 		ErrorOutputPaths: []string{"stderr"},
 	}
-
-	logger, _ := config.Build()
-	return logger.Sugar()
 }
 
 func (log Logger) Debug(ctx context.Context, template string, args ...interface{}) {
 	span, ok := tracer.SpanFromContext(ctx)
 	if ok {
-		log.internalLogger.Debugw(fmt.Sprintf(template, args...), "dd.trace_id", span.Context().TraceID(), "dd.span_id", span.Context().SpanID())
+		log.internalLogger.Debugw(fmt.Sprintf(template, args...), log.traceFields(span)...)
 	} else {
 		log.internalLogger.Debugf(template, args...)
 	}
@@ -113,7 +192,7 @@ func (log Logger) Debug(ctx context.Context, template string, args ...interface{
 func (log Logger) Info(ctx context.Context, template string, args ...interface{}) {
 	span, ok := tracer.SpanFromContext(ctx)
 	if ok {
-		log.internalLogger.Infow(fmt.Sprintf(template, args...), "dd.trace_id", span.Context().TraceID(), "dd.span_id", span.Context().SpanID())
+		log.internalLogger.Infow(fmt.Sprintf(template, args...), log.traceFields(span)...)
 	} else {
 		log.internalLogger.Infof(template, args...)
 	}
@@ -122,7 +201,7 @@ func (log Logger) Info(ctx context.Context, template string, args ...interface{}
 func (log Logger) Warn(ctx context.Context, template string, args ...interface{}) {
 	span, ok := tracer.SpanFromContext(ctx)
 	if ok {
-		log.internalLogger.Warnw(fmt.Sprintf(template, args...), "dd.trace_id", span.Context().TraceID(), "dd.span_id", span.Context().SpanID())
+		log.internalLogger.Warnw(fmt.Sprintf(template, args...), log.traceFields(span)...)
 	} else {
 		log.internalLogger.Warnf(template, args...)
 	}
@@ -131,17 +210,193 @@ func (log Logger) Warn(ctx context.Context, template string, args ...interface{}
 func (log Logger) Error(ctx context.Context, template string, args ...interface{}) {
 	span, ok := tracer.SpanFromContext(ctx)
 	if ok {
-		span.SetTag("error", fmt.Errorf(template, args...))
-		log.internalLogger.Errorw(fmt.Sprintf(template, args...), "dd.trace_id", span.Context().TraceID(), "dd.span_id", span.Context().SpanID())
+		err := fmt.Errorf(template, args...)
+		log.recordSpanError(span, err, errorStack(err, 2))
+		log.internalLogger.Errorw(fmt.Sprintf(template, args...), log.traceFields(span)...)
 	} else {
 		log.internalLogger.Errorf(template, args...)
 	}
 }
 
+// Debugw logs a message with the given key-value pairs as structured fields.
+// Example:
+//
+//	logger.Debugw(ctx, "Order created", "order_id", order.ID, "customer_id", order.CustomerID)
+func (log Logger) Debugw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	log.internalLogger.Debugw(msg, log.withTraceFields(ctx, keysAndValues)...)
+}
+
+func (log Logger) Infow(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	log.internalLogger.Infow(msg, log.withTraceFields(ctx, keysAndValues)...)
+}
+
+func (log Logger) Warnw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	log.internalLogger.Warnw(msg, log.withTraceFields(ctx, keysAndValues)...)
+}
+
+// Errorw logs a message with the given key-value pairs as structured fields, and records the error on the span.
+// The first error value in keysAndValues is recorded, or the message when there is none.
+func (log Logger) Errorw(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	span, ok := tracer.SpanFromContext(ctx)
+	if ok {
+		err := firstError(msg, keysAndValues)
+		log.recordSpanError(span, err, errorStack(err, 2))
+	}
+	log.internalLogger.Errorw(msg, log.withTraceFields(ctx, keysAndValues)...)
+}
+
+// Logw logs a message at the given level with the given key-value pairs as structured fields.
+// Unlike Errorw it never records an error on the span, which is useful when the span status is set elsewhere.
+// Example:
+//
+//	logger.Logw(ctx, zapcore.ErrorLevel, "HTTP request", "http.status_code", 500)
+func (log Logger) Logw(ctx context.Context, level zapcore.Level, msg string, keysAndValues ...interface{}) {
+	log.internalLogger.Logw(level, msg, log.withTraceFields(ctx, keysAndValues)...)
+}
+
+// DebugFields logs a message with the given typed zap fields.
+// Example:
+//
+//	logger.DebugFields(ctx, "Order created", zap.Int64("order_id", order.ID), zap.Duration("duration", duration))
+func (log Logger) DebugFields(ctx context.Context, msg string, fields ...zap.Field) {
+	log.internalLogger.Desugar().Debug(msg, log.withTraceZapFields(ctx, fields)...)
+}
+
+func (log Logger) InfoFields(ctx context.Context, msg string, fields ...zap.Field) {
+	log.internalLogger.Desugar().Info(msg, log.withTraceZapFields(ctx, fields)...)
+}
+
+func (log Logger) WarnFields(ctx context.Context, msg string, fields ...zap.Field) {
+	log.internalLogger.Desugar().Warn(msg, log.withTraceZapFields(ctx, fields)...)
+}
+
+// ErrorFields logs a message with the given typed zap fields, and records the error on the span.
+// The first error field is recorded, or the message when there is none.
+func (log Logger) ErrorFields(ctx context.Context, msg string, fields ...zap.Field) {
+	span, ok := tracer.SpanFromContext(ctx)
+	if ok {
+		err := firstErrorField(msg, fields)
+		log.recordSpanError(span, err, errorStack(err, 2))
+	}
+	log.internalLogger.Desugar().Error(msg, log.withTraceZapFields(ctx, fields)...)
+}
+
+func firstError(msg string, keysAndValues []interface{}) error {
+	for _, value := range keysAndValues {
+		if err, ok := value.(error); ok {
+			return err
+		}
+		if field, ok := value.(zap.Field); ok {
+			if err := fieldError(field); err != nil {
+				return err
+			}
+		}
+	}
+	return errors.New(msg)
+}
+
+func firstErrorField(msg string, fields []zap.Field) error {
+	for _, field := range fields {
+		if err := fieldError(field); err != nil {
+			return err
+		}
+	}
+	return errors.New(msg)
+}
+
+func fieldError(field zap.Field) error {
+	if err, ok := field.Interface.(error); ok && field.Type == zapcore.ErrorType {
+		return err
+	}
+	return nil
+}
+
+// With returns a child logger that adds the key-value pairs to every log line.
+// The child shares the core of the parent, so creating it is cheap.
+// Example:
+//
+//	requestLogger := logger.With("request_id", requestID, "tenant", tenant)
+//	requestLogger.Info(ctx, "Handling request")
+func (log Logger) With(keysAndValues ...interface{}) Logger {
+	child := log
+	child.internalLogger = log.internalLogger.With(keysAndValues...)
+	return child
+}
+
+// Named returns a child logger with the name appended to the name of the parent, separated by a period.
+func (log Logger) Named(name string) Logger {
+	child := log
+	child.internalLogger = log.internalLogger.Named(name)
+	return child
+}
+
 func (log Logger) Fatal(args ...interface{}) {
 	log.internalLogger.Fatal(args...)
 }
 
+// FatalContext logs the message with the trace correlation of the span in the context, records it as error on
+// the open spans of the trace and finishes them. The open spans are the span in the context, the spans started by
+// apm.Trace, apm.TraceValue and Apm.StartSpanFromContext on the way to it and the root span. The tracer only sends
+// a trace once all of its spans are finished, so the complete trace is flushed and the logger is synced before
+// the process exits and the spans explaining the crash are not lost.
+// Example:
+//
+//	logger.FatalContext(ctx, "Failed to connect to the database: %s", err)
+func (log Logger) FatalContext(ctx context.Context, template string, args ...interface{}) {
+	msg := fmt.Sprintf(template, args...)
+	err := errors.New(msg)
+	fields := log.finishSpansWithError(ctx, err, errorStack(err, 2))
+
+	tracer.Flush()
+	log.internalLogger.Fatalw(msg, fields...)
+}
+
+// PanicContext logs the message with the trace correlation of the span in the context and records it as error on
+// the span. The tracer is flushed before panicking with the message. No span is finished, as the panic may be
+// recovered, like by the recovery middleware which sets the status of the request on the root span.
+func (log Logger) PanicContext(ctx context.Context, template string, args ...interface{}) {
+	msg := fmt.Sprintf(template, args...)
+
+	var fields []interface{}
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		err := errors.New(msg)
+		fields = log.traceFields(span)
+		log.recordSpanError(span, err, errorStack(err, 2))
+	}
+
+	tracer.Flush()
+	log.internalLogger.Panicw(msg, fields...)
+}
+
+// finishSpansWithError records the error on the open spans of the trace of the span in the context and finishes
+// them from the innermost to the root span, so the trace is complete. It returns the trace correlation fields
+// of the span.
+func (log Logger) finishSpansWithError(ctx context.Context, err error, stack string) []interface{} {
+	span, ok := tracer.SpanFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	spans := []*tracer.Span{span}
+	tracked := openspans.FromContext(ctx)
+	for i := len(tracked) - 1; i >= 0; i-- {
+		if tracked[i].Context().TraceID() == span.Context().TraceID() && !slices.Contains(spans, tracked[i]) {
+			spans = append(spans, tracked[i])
+		}
+	}
+	if root := span.Root(); root != nil && !slices.Contains(spans, root) {
+		spans = append(spans, root)
+	}
+
+	fields := log.traceFields(span)
+	for _, openSpan := range spans {
+		log.recordSpanError(openSpan, err, stack)
+		openSpan.Finish()
+	}
+
+	return fields
+}
+
 func (log Logger) Sync() {
 	_ = log.internalLogger.Sync()
 }
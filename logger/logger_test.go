@@ -4,10 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"slices"
+	"strconv"
 	"testing"
 
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/ext"
 	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
 	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/config"
+	"github.com/YourSurpriseCom/go-datadog-apm/v2/internal/openspans"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest/observer"
@@ -69,6 +75,14 @@ func TestNewLogger(t *testing.T) {
 				Encoding: "console",
 			})},
 		},
+		{
+			name:          "use option to provide apm config",
+			expectedLevel: zapcore.ErrorLevel,
+			option: []LoggerOption{WithApmConfig(config.Config{
+				LogLevel:    zapcore.ErrorLevel,
+				LogEncoding: "console",
+			})},
+		},
 		{
 			name:          "use option to provide logger name",
 			expectedLevel: zapcore.InfoLevel,
@@ -107,6 +121,72 @@ func TestNewLogger(t *testing.T) {
 	}
 }
 
+func TestServiceFields(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		option         []LoggerOption
+		expectedFields map[string]interface{}
+	}{
+		{
+			name:           "no service tags",
+			expectedFields: map[string]interface{}{},
+		},
+		{
+			name: "service tags from env vars",
+			env:  map[string]string{"DD_SERVICE": "env-service", "DD_ENV": "staging", "DD_VERSION": "1.0.0"},
+			expectedFields: map[string]interface{}{
+				"dd.service": "env-service",
+				"dd.env":     "staging",
+				"dd.version": "1.0.0",
+			},
+		},
+		{
+			name:   "options override env vars",
+			env:    map[string]string{"DD_SERVICE": "env-service", "DD_ENV": "staging"},
+			option: []LoggerOption{WithService("my-service"), WithVersion("2.0.0")},
+			expectedFields: map[string]interface{}{
+				"dd.service": "my-service",
+				"dd.env":     "staging",
+				"dd.version": "2.0.0",
+			},
+		},
+		{
+			name:   "service tags from apm config",
+			option: []LoggerOption{WithApmConfig(config.Config{Service: "config-service", Env: "production"})},
+			expectedFields: map[string]interface{}{
+				"dd.service": "config-service",
+				"dd.env":     "production",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DD_SERVICE", "DD_ENV", "DD_VERSION"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			captureLog, logs := setupLogsCapture()
+			options := append(tt.option, func(l *Logger) {
+				l.internalLogger = captureLog
+			})
+			logger := NewLogger(options...)
+
+			logger.Info(context.Background(), "service message")
+			logger.With("order_id", "order-1").Infow(context.Background(), "child message")
+
+			for _, logEntry := range logs.All() {
+				fields := logEntry.ContextMap()
+				delete(fields, "order_id")
+				if !reflect.DeepEqual(fields, tt.expectedFields) {
+					t.Errorf("Fields of '%s' incorrect, expected '%v' got '%v'", logEntry.Message, tt.expectedFields, fields)
+				}
+			}
+		})
+	}
+}
+
 func TestLogFunctions(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
@@ -115,9 +195,9 @@ func TestLogFunctions(t *testing.T) {
 	span, spanContext := tracer.StartSpanFromContext(ctx, "test.span")
 	span.Finish()
 
-	// Get the actual trace ID and span ID from the span
-	actualTraceID := span.Context().TraceID()
-	actualSpanID := span.Context().SpanID()
+	// Get the actual trace ID and span ID from the span, the trace ID is logged as the decimal lower 64 bits
+	actualTraceID := strconv.FormatUint(span.Context().TraceIDLower(), 10)
+	actualSpanID := strconv.FormatUint(span.Context().SpanID(), 10)
 
 	tests := []struct {
 		name            string
@@ -126,7 +206,7 @@ func TestLogFunctions(t *testing.T) {
 		logLevel        zapcore.Level
 		wantTrace       bool
 		expectedTraceId string
-		expectedSpanId  uint64
+		expectedSpanId  string
 	}{
 		{
 			name:            "debug with trace context",
@@ -234,7 +314,7 @@ func TestLogFunctions(t *testing.T) {
 					}
 
 					if logEntry.ContextMap()["dd.span_id"] != tt.expectedSpanId {
-						t.Errorf("Message dd.span_id incorrect, expected '%d' got '%d'", tt.expectedSpanId, logEntry.ContextMap()["dd.span_id"])
+						t.Errorf("Message dd.span_id incorrect, expected '%s' got '%s'", tt.expectedSpanId, logEntry.ContextMap()["dd.span_id"])
 					}
 				}
 			}
@@ -242,6 +322,204 @@ func TestLogFunctions(t *testing.T) {
 	}
 }
 
+func TestStructuredLogFunctions(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+	defer span.Finish()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		logLevel  zapcore.Level
+		typed     bool
+		wantTrace bool
+	}{
+		{name: "debugw with trace context", ctx: spanContext, logLevel: zap.DebugLevel, wantTrace: true},
+		{name: "infow without trace context", ctx: context.Background(), logLevel: zap.InfoLevel},
+		{name: "warnw with trace context", ctx: spanContext, logLevel: zap.WarnLevel, wantTrace: true},
+		{name: "errorw with trace context", ctx: spanContext, logLevel: zap.ErrorLevel, wantTrace: true},
+		{name: "debug fields with trace context", ctx: spanContext, logLevel: zap.DebugLevel, typed: true, wantTrace: true},
+		{name: "info fields with trace context", ctx: spanContext, logLevel: zap.InfoLevel, typed: true, wantTrace: true},
+		{name: "warn fields without trace context", ctx: context.Background(), logLevel: zap.WarnLevel, typed: true},
+		{name: "error fields with trace context", ctx: spanContext, logLevel: zap.ErrorLevel, typed: true, wantTrace: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			logger := Logger{
+				internalLogger: zap.New(core).Sugar(),
+			}
+
+			if tt.typed {
+				fields := []zap.Field{zap.String("order_id", "order-1"), zap.Int("quantity", 3)}
+				switch tt.logLevel {
+				case zap.DebugLevel:
+					logger.DebugFields(tt.ctx, "structured message", fields...)
+				case zap.InfoLevel:
+					logger.InfoFields(tt.ctx, "structured message", fields...)
+				case zap.WarnLevel:
+					logger.WarnFields(tt.ctx, "structured message", fields...)
+				case zap.ErrorLevel:
+					logger.ErrorFields(tt.ctx, "structured message", fields...)
+				}
+			} else {
+				keysAndValues := []interface{}{"order_id", "order-1", "quantity", 3}
+				switch tt.logLevel {
+				case zap.DebugLevel:
+					logger.Debugw(tt.ctx, "structured message", keysAndValues...)
+				case zap.InfoLevel:
+					logger.Infow(tt.ctx, "structured message", keysAndValues...)
+				case zap.WarnLevel:
+					logger.Warnw(tt.ctx, "structured message", keysAndValues...)
+				case zap.ErrorLevel:
+					logger.Errorw(tt.ctx, "structured message", keysAndValues...)
+				}
+			}
+
+			logEntries := logs.All()
+			if len(logEntries) != 1 {
+				t.Fatalf("expected 1 log, got %d", len(logEntries))
+			}
+
+			logEntry := logEntries[0]
+			if logEntry.Message != "structured message" {
+				t.Errorf("Message incorrect, expected 'structured message' got '%s'", logEntry.Message)
+			}
+			if logEntry.Level != tt.logLevel {
+				t.Errorf("Level incorrect, expected '%s' got '%s'", tt.logLevel, logEntry.Level)
+			}
+
+			fields := logEntry.ContextMap()
+			if fields["order_id"] != "order-1" {
+				t.Errorf("Field order_id incorrect, expected 'order-1' got '%v'", fields["order_id"])
+			}
+			if fields["quantity"] != int64(3) {
+				t.Errorf("Field quantity incorrect, expected '3' got '%v'", fields["quantity"])
+			}
+
+			_, hasTraceID := fields["dd.trace_id"]
+			if hasTraceID != tt.wantTrace {
+				t.Errorf("Expected dd.trace_id presence to be %v", tt.wantTrace)
+			}
+			if spanID := strconv.FormatUint(span.Context().SpanID(), 10); tt.wantTrace && fields["dd.span_id"] != spanID {
+				t.Errorf("Message dd.span_id incorrect, expected '%s' got '%v'", spanID, fields["dd.span_id"])
+			}
+		})
+	}
+}
+
+func TestErrorwRecordsError(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	captureLog, _ := setupLogsCapture()
+	logger := Logger{
+		internalLogger: captureLog,
+	}
+
+	span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+	logger.Errorw(spanContext, "order failed", "order_id", "order-1", "error", fmt.Errorf("payment declined"))
+	span.Finish()
+
+	finishedSpan := mt.FinishedSpans()[0]
+	if finishedSpan.Tag(ext.ErrorMsg) != "payment declined" {
+		t.Errorf("Span error incorrect, expected 'payment declined' got '%v'", finishedSpan.Tag(ext.ErrorMsg))
+	}
+
+	span, spanContext = tracer.StartSpanFromContext(context.Background(), "test.span")
+	logger.ErrorFields(spanContext, "order failed", zap.String("order_id", "order-1"))
+	span.Finish()
+
+	finishedSpan = mt.FinishedSpans()[1]
+	if finishedSpan.Tag(ext.ErrorMsg) != "order failed" {
+		t.Errorf("Span error incorrect, expected 'order failed' got '%v'", finishedSpan.Tag(ext.ErrorMsg))
+	}
+}
+
+func TestLogw(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	captureLog, logs := setupLogsCapture()
+	logger := Logger{
+		internalLogger: captureLog,
+	}
+
+	span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+	logger.Logw(spanContext, zapcore.ErrorLevel, "request failed", "status", 500)
+	span.Finish()
+
+	logEntries := logs.All()
+	if len(logEntries) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(logEntries))
+	}
+	if logEntries[0].Level != zapcore.ErrorLevel {
+		t.Errorf("Level incorrect, expected '%s' got '%s'", zapcore.ErrorLevel, logEntries[0].Level)
+	}
+	if logEntries[0].ContextMap()["status"] != int64(500) {
+		t.Errorf("Message status incorrect, expected '500' got '%v'", logEntries[0].ContextMap()["status"])
+	}
+	if logEntries[0].ContextMap()["dd.trace_id_128"] != span.Context().TraceID() {
+		t.Errorf("Message dd.trace_id_128 incorrect, expected '%s' got '%v'", span.Context().TraceID(), logEntries[0].ContextMap()["dd.trace_id_128"])
+	}
+
+	if mt.FinishedSpans()[0].Tag(ext.ErrorMsg) != nil {
+		t.Errorf("expected no error on the span, got '%v'", mt.FinishedSpans()[0].Tag(ext.ErrorMsg))
+	}
+}
+
+func TestWith(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+	defer span.Finish()
+
+	captureLog, logs := setupLogsCapture()
+	logger := Logger{
+		internalLogger: captureLog,
+	}
+
+	child := logger.With("request_id", "request-1", "tenant", "tenant-1")
+	child.Info(spanContext, "child message")
+	child.Infow(spanContext, "child structured message", "order_id", "order-1")
+	logger.Info(spanContext, "parent message")
+
+	logEntries := logs.All()
+	if len(logEntries) != 3 {
+		t.Fatalf("expected 3 logs, got %d", len(logEntries))
+	}
+
+	for _, logEntry := range logEntries[:2] {
+		fields := logEntry.ContextMap()
+		if fields["request_id"] != "request-1" || fields["tenant"] != "tenant-1" {
+			t.Errorf("expected bound fields on '%s', got '%v'", logEntry.Message, fields)
+		}
+		if fields["dd.trace_id_128"] != span.Context().TraceID() {
+			t.Errorf("Message dd.trace_id_128 incorrect, expected '%s' got '%v'", span.Context().TraceID(), fields["dd.trace_id_128"])
+		}
+	}
+
+	if _, ok := logEntries[2].ContextMap()["request_id"]; ok {
+		t.Error("expected parent logger not to contain the bound fields")
+	}
+}
+
+func TestNamed(t *testing.T) {
+	logger := NewLogger(WithName("parent"))
+	child := logger.Named("child")
+
+	if child.Name() != "parent.child" {
+		t.Errorf("Expected child logger name to be 'parent.child', got '%s'", child.Name())
+	}
+	if logger.Name() != "parent" {
+		t.Errorf("Expected parent logger name to be 'parent', got '%s'", logger.Name())
+	}
+}
+
 func TestFatalLogFunction(t *testing.T) {
 	captureLogger, logsCollector := setupLogsCapture()
 	logger := Logger{
@@ -272,6 +550,100 @@ func TestFatalLogFunction(t *testing.T) {
 	}
 }
 
+func TestFatalAndPanicContext(t *testing.T) {
+	tests := []struct {
+		name                string
+		log                 func(logger Logger, ctx context.Context)
+		expectedLevel       zapcore.Level
+		expectedFinishedOps []string
+	}{
+		{
+			name: "fatal",
+			log: func(logger Logger, ctx context.Context) {
+				logger.FatalContext(ctx, "startup failed: %s", "database unavailable")
+			},
+			expectedLevel: zapcore.FatalLevel,
+			// every open span of the trace is finished, so the trace is complete and flushed
+			expectedFinishedOps: []string{"test.repository", "test.service", "test.handler"},
+		},
+		{
+			name: "panic",
+			log: func(logger Logger, ctx context.Context) {
+				logger.PanicContext(ctx, "startup failed: %s", "database unavailable")
+			},
+			expectedLevel: zapcore.PanicLevel,
+			// the panic may be recovered, so no span is finished
+			expectedFinishedOps: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			captureLogger, logsCollector := setupLogsCapture()
+			logger := Logger{
+				internalLogger: captureLogger,
+			}
+
+			// handler -> service -> repository, the service span is tracked like the spans of apm.Trace
+			handler, handlerContext := tracer.StartSpanFromContext(context.Background(), "test.handler")
+			service, serviceContext := tracer.StartSpanFromContext(handlerContext, "test.service")
+			serviceContext = openspans.ContextWithSpan(serviceContext, service)
+			span, spanContext := tracer.StartSpanFromContext(serviceContext, "test.repository")
+
+			var panicked interface{}
+			func() {
+				defer func() {
+					panicked = recover()
+				}()
+				tt.log(logger, spanContext)
+				t.Error("logger did not terminate")
+			}()
+
+			if panicked == nil {
+				t.Fatal("expected panic")
+			}
+
+			logEntries := logsCollector.All()
+			if len(logEntries) != 1 {
+				t.Fatalf("expected 1 log, got %d", len(logEntries))
+			}
+			logEntry := logEntries[0]
+			if logEntry.Message != "startup failed: database unavailable" {
+				t.Errorf("Message incorrect, expected 'startup failed: database unavailable' got '%s'", logEntry.Message)
+			}
+			if logEntry.Level != tt.expectedLevel {
+				t.Errorf("Level incorrect, expected '%s' got '%s'", tt.expectedLevel, logEntry.Level)
+			}
+			if logEntry.ContextMap()["dd.trace_id_128"] != span.Context().TraceID() {
+				t.Errorf("Message dd.trace_id_128 incorrect, expected '%s' got '%v'", span.Context().TraceID(), logEntry.ContextMap()["dd.trace_id_128"])
+			}
+
+			// the mocktracer reports the open spans as finished on Flush, so the finished spans are checked
+			// by setting a tag like the recovery middleware does, which is ignored once a span is finished
+			for _, openSpan := range []*tracer.Span{span, service, handler} {
+				openSpan.SetTag(ext.HTTPCode, "500")
+			}
+			finished := map[string]bool{}
+			for _, finishedSpan := range mt.FinishedSpans() {
+				finished[finishedSpan.OperationName()] = finishedSpan.Tag(ext.HTTPCode) == nil
+				if finishedSpan.OperationName() == "test.repository" || finished[finishedSpan.OperationName()] {
+					if finishedSpan.Tag(ext.ErrorMsg) != "startup failed: database unavailable" {
+						t.Errorf("Span error of '%s' incorrect, expected 'startup failed: database unavailable' got '%v'", finishedSpan.OperationName(), finishedSpan.Tag(ext.ErrorMsg))
+					}
+				}
+			}
+			for _, operation := range []string{"test.repository", "test.service", "test.handler"} {
+				if expected := slices.Contains(tt.expectedFinishedOps, operation); finished[operation] != expected {
+					t.Errorf("Finished state of '%s' incorrect, expected '%v' got '%v'", operation, expected, finished[operation])
+				}
+			}
+		})
+	}
+}
+
 func TestSync(t *testing.T) {
 	t.Run("successful sync", func(t *testing.T) {
 		captureLogger, _ := setupLogsCapture()
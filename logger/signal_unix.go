@@ -0,0 +1,38 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// HandleLevelSignals increases the log verbosity one level on SIGUSR1 and decreases it on SIGUSR2.
+// The level reverts automatically when no signal is received within revertAfter.
+// The returned function stops handling the signals and reverts the level.
+// Example:
+//
+//	stop := logger.HandleLevelSignals(10 * time.Minute)
+//	defer stop()
+func (log Logger) HandleLevelSignals(revertAfter time.Duration) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		log.watchLevelSignals(signals, syscall.SIGUSR1, syscall.SIGUSR2, revertAfter)
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(signals)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package logger
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestHandleLevelSignals(t *testing.T) {
+	logger := NewLogger(WithConfig(zap.Config{
+		Level:    zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		Encoding: "console",
+	}))
+
+	stop := logger.HandleLevelSignals(time.Minute)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("Unexpected error while sending signal: %v", err)
+	}
+	waitForLevel(t, logger, zapcore.DebugLevel)
+
+	stop()
+	stop()
+
+	if logger.Level() != zapcore.InfoLevel {
+		t.Errorf("Expected level to revert to '%s' when stopped, got '%s'", zapcore.InfoLevel, logger.Level())
+	}
+}
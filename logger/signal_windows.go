@@ -0,0 +1,10 @@
+//go:build windows
+
+package logger
+
+import "time"
+
+// HandleLevelSignals is a no-op on Windows, which has no SIGUSR1 and SIGUSR2 signals.
+func (log Logger) HandleLevelSignals(revertAfter time.Duration) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler is a slog.Handler that writes the records to the zap core of a Logger.
+type slogHandler struct {
+	log    Logger
+	fields []zap.Field
+}
+
+// NewSlogHandler creates a slog.Handler that writes to the same zap core as the logger.
+// The trace correlation fields of the span in the record context are added to every record.
+func NewSlogHandler(log Logger) slog.Handler {
+	return &slogHandler{log: log}
+}
+
+// NewSlogLogger creates a *slog.Logger that writes to the same zap core as the logger.
+// Example:
+//
+//	slog.SetDefault(NewSlogLogger(logger))
+//	slog.InfoContext(ctx, "Order created", "order_id", order.ID)
+func NewSlogLogger(log Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(log))
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.log.internalLogger.Desugar().Core().Enabled(zapLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	checkedEntry := h.log.internalLogger.Desugar().Check(zapLevel(record.Level), record.Message)
+	if checkedEntry == nil {
+		return nil
+	}
+
+	if !record.Time.IsZero() {
+		checkedEntry.Time = record.Time
+	}
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		checkedEntry.Caller = zapcore.NewEntryCaller(record.PC, frame.File, frame.Line, true)
+	}
+
+	fields := slices.Clone(h.fields)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = append(fields, slogFields(attr)...)
+		return true
+	})
+
+	checkedEntry.Write(h.log.withTraceZapFields(ctx, fields)...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := slices.Clone(h.fields)
+	for _, attr := range attrs {
+		fields = append(fields, slogFields(attr)...)
+	}
+	return &slogHandler{log: h.log, fields: fields}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{log: h.log, fields: append(slices.Clone(h.fields), zap.Namespace(name))}
+}
+
+func zapLevel(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// slogFields converts an attribute to zap fields, an attribute of an unnamed group results in multiple fields.
+func slogFields(attr slog.Attr) []zap.Field {
+	value := attr.Value.Resolve()
+	if attr.Key == "" && value.Kind() != slog.KindGroup {
+		return nil
+	}
+
+	switch value.Kind() {
+	case slog.KindGroup:
+		var fields []zap.Field
+		for _, groupAttr := range value.Group() {
+			fields = append(fields, slogFields(groupAttr)...)
+		}
+		if len(fields) == 0 || attr.Key == "" {
+			return fields
+		}
+		return []zap.Field{zap.Dict(attr.Key, fields...)}
+	case slog.KindString:
+		return []zap.Field{zap.String(attr.Key, value.String())}
+	case slog.KindInt64:
+		return []zap.Field{zap.Int64(attr.Key, value.Int64())}
+	case slog.KindUint64:
+		return []zap.Field{zap.Uint64(attr.Key, value.Uint64())}
+	case slog.KindFloat64:
+		return []zap.Field{zap.Float64(attr.Key, value.Float64())}
+	case slog.KindBool:
+		return []zap.Field{zap.Bool(attr.Key, value.Bool())}
+	case slog.KindDuration:
+		return []zap.Field{zap.Duration(attr.Key, value.Duration())}
+	case slog.KindTime:
+		return []zap.Field{zap.Time(attr.Key, value.Time())}
+	default:
+		if err, ok := value.Any().(error); ok {
+			return []zap.Field{zap.NamedError(attr.Key, err)}
+		}
+		return []zap.Field{zap.Any(attr.Key, value.Any())}
+	}
+}
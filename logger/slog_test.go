@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/mocktracer"
+	"github.com/DataDog/dd-trace-go/v2/ddtrace/tracer"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSlogHandler(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	span, spanContext := tracer.StartSpanFromContext(context.Background(), "test.span")
+	defer span.Finish()
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		log            func(ctx context.Context, slogger *slog.Logger)
+		expectedLevel  zapcore.Level
+		expectedFields map[string]interface{}
+		wantTrace      bool
+	}{
+		{
+			name: "info with trace context",
+			ctx:  spanContext,
+			log: func(ctx context.Context, slogger *slog.Logger) {
+				slogger.InfoContext(ctx, "slog message", "order_id", "order-1", "quantity", 3)
+			},
+			expectedLevel:  zapcore.InfoLevel,
+			expectedFields: map[string]interface{}{"order_id": "order-1", "quantity": int64(3)},
+			wantTrace:      true,
+		},
+		{
+			name: "warn without trace context",
+			ctx:  context.Background(),
+			log: func(ctx context.Context, slogger *slog.Logger) {
+				slogger.WarnContext(ctx, "slog message", slog.Bool("retry", true))
+			},
+			expectedLevel:  zapcore.WarnLevel,
+			expectedFields: map[string]interface{}{"retry": true},
+		},
+		{
+			name: "error with bound attributes",
+			ctx:  spanContext,
+			log: func(ctx context.Context, slogger *slog.Logger) {
+				slogger.With("request_id", "request-1").ErrorContext(ctx, "slog message", "error", errors.New("failed"))
+			},
+			expectedLevel:  zapcore.ErrorLevel,
+			expectedFields: map[string]interface{}{"request_id": "request-1", "error": "failed"},
+			wantTrace:      true,
+		},
+		{
+			name: "debug with groups",
+			ctx:  spanContext,
+			log: func(ctx context.Context, slogger *slog.Logger) {
+				slogger.WithGroup("http").With("method", "GET").DebugContext(ctx, "slog message", slog.Group("response", "status", 200))
+			},
+			expectedLevel: zapcore.DebugLevel,
+			expectedFields: map[string]interface{}{
+				"http": map[string]interface{}{
+					"method":   "GET",
+					"response": map[string]interface{}{"status": int64(200)},
+				},
+			},
+			wantTrace: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			logger := Logger{
+				internalLogger: zap.New(core).Sugar(),
+			}
+
+			tt.log(tt.ctx, NewSlogLogger(logger))
+
+			logEntries := logs.All()
+			if len(logEntries) != 1 {
+				t.Fatalf("expected 1 log, got %d", len(logEntries))
+			}
+
+			logEntry := logEntries[0]
+			if logEntry.Message != "slog message" {
+				t.Errorf("Message incorrect, expected 'slog message' got '%s'", logEntry.Message)
+			}
+			if logEntry.Level != tt.expectedLevel {
+				t.Errorf("Level incorrect, expected '%s' got '%s'", tt.expectedLevel, logEntry.Level)
+			}
+
+			fields := logEntry.ContextMap()
+			for key, expected := range tt.expectedFields {
+				if !reflect.DeepEqual(fields[key], expected) {
+					t.Errorf("Field %s incorrect, expected '%v' got '%v'", key, expected, fields[key])
+				}
+			}
+
+			if tt.wantTrace {
+				if fields["dd.trace_id_128"] != span.Context().TraceID() {
+					t.Errorf("Message dd.trace_id_128 incorrect, expected '%s' got '%v'", span.Context().TraceID(), fields["dd.trace_id_128"])
+				}
+				if spanID := strconv.FormatUint(span.Context().SpanID(), 10); fields["dd.span_id"] != spanID {
+					t.Errorf("Message dd.span_id incorrect, expected '%s' got '%v'", spanID, fields["dd.span_id"])
+				}
+			} else if _, ok := fields["dd.trace_id"]; ok {
+				t.Error("expected no dd.trace_id without span in context")
+			}
+		})
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	core, _ := observer.New(zap.WarnLevel)
+	handler := NewSlogHandler(Logger{internalLogger: zap.New(core).Sugar()})
+
+	if handler.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("Expected slog info level to be disabled")
+	}
+	if !handler.Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("Expected slog warn level to be enabled")
+	}
+}
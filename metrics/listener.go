@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Listener is a local stand-in for DogStatsD which receives the metrics over UDP or a Unix domain socket,
+// to test the metrics on the wire, like "orders.created:1|c|#service:orders".
+type Listener struct {
+	conn    net.PacketConn
+	addr    string
+	pending []string
+}
+
+// ListenUDP starts a Listener on a free UDP port of 127.0.0.1.
+// Example:
+//
+//	listener, err := metrics.ListenUDP()
+//	m, err := metrics.NewMetrics(metrics.WithAddr(listener.Addr()))
+func ListenUDP() (*Listener, error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Listener{conn: conn, addr: conn.LocalAddr().String()}, nil
+}
+
+// ListenUnixgram starts a Listener on a Unix datagram socket at the path.
+func ListenUnixgram(path string) (*Listener, error) {
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Listener{conn: conn, addr: "unix://" + path}, nil
+}
+
+// Addr returns the address to pass to WithAddr.
+func (l *Listener) Addr() string {
+	return l.addr
+}
+
+// Next returns the next metric line, or an error when none is received within the timeout.
+func (l *Listener) Next(timeout time.Duration) (string, error) {
+	if len(l.pending) == 0 {
+		if err := l.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return "", err
+		}
+
+		buffer := make([]byte, 65536)
+		n, _, err := l.conn.ReadFrom(buffer)
+		if err != nil {
+			return "", err
+		}
+		l.pending = strings.Split(strings.TrimSuffix(string(buffer[:n]), "\n"), "\n")
+	}
+
+	line := l.pending[0]
+	l.pending = l.pending[1:]
+	return line, nil
+}
+
+// NextWithPrefix returns the next metric line starting with the prefix, like "orders.created:",
+// skipping the other lines.
+func (l *Listener) NextWithPrefix(prefix string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		line, err := l.Next(time.Until(deadline))
+		if err != nil {
+			return "", fmt.Errorf("no metric starting with '%s': %w", prefix, err)
+		}
+		if strings.HasPrefix(line, prefix) {
+			return line, nil
+		}
+	}
+}
+
+func (l *Listener) Close() error {
+	return l.conn.Close()
+}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"cmp"
+	"net"
+	"os"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+const defaultPort = "8125"
+
+// Metrics sends metrics to DogStatsD, tagged with the service, env and version and the tags of every call.
+type Metrics interface {
+	Count(name string, value int64, tags ...string)
+	Gauge(name string, value float64, tags ...string)
+	Histogram(name string, value float64, tags ...string)
+	Distribution(name string, value float64, tags ...string)
+	Timing(name string, value time.Duration, tags ...string)
+	Close() error
+}
+
+type options struct {
+	addr      string
+	agentHost string
+	service   string
+	env       string
+	version   string
+	tags      []string
+}
+
+type MetricsOption func(*options)
+
+// WithAddr sets the address of DogStatsD, like "localhost:8125" or "unix:///var/run/datadog/dsd.socket".
+// When not set, DD_DOGSTATSD_URL is used, or port 8125 (or DD_DOGSTATSD_PORT) of the agent host.
+func WithAddr(addr string) MetricsOption {
+	return func(o *options) {
+		o.addr = addr
+	}
+}
+
+// WithAgentHost sets the host of the agent, overriding DD_AGENT_HOST.
+func WithAgentHost(host string) MetricsOption {
+	return func(o *options) {
+		o.agentHost = host
+	}
+}
+
+// WithService sets the service tag added to every metric, unless DD_SERVICE is set.
+func WithService(service string) MetricsOption {
+	return func(o *options) {
+		o.service = service
+	}
+}
+
+// WithEnv sets the env tag added to every metric, unless DD_ENV is set.
+func WithEnv(env string) MetricsOption {
+	return func(o *options) {
+		o.env = env
+	}
+}
+
+// WithVersion sets the version tag added to every metric, unless DD_VERSION is set.
+func WithVersion(version string) MetricsOption {
+	return func(o *options) {
+		o.version = version
+	}
+}
+
+// WithTags adds tags to every metric, like "team:checkout".
+func WithTags(tags ...string) MetricsOption {
+	return func(o *options) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+func newOptions(opts []MetricsOption) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// globalTags returns the tags added to every metric. The DogStatsD client adds the DD_SERVICE, DD_ENV
+// and DD_VERSION tags itself, so the tags of the options are only added when these are not set,
+// a metric with two service tags would be counted for both services.
+func (o options) globalTags() []string {
+	var tags []string
+
+	if o.service != "" && os.Getenv("DD_SERVICE") == "" {
+		tags = append(tags, "service:"+o.service)
+	}
+	if o.env != "" && os.Getenv("DD_ENV") == "" {
+		tags = append(tags, "env:"+o.env)
+	}
+	if o.version != "" && os.Getenv("DD_VERSION") == "" {
+		tags = append(tags, "version:"+o.version)
+	}
+
+	return append(tags, o.tags...)
+}
+
+// address returns the address of DogStatsD, an empty address lets the DogStatsD client resolve
+// DD_DOGSTATSD_URL, or DD_AGENT_HOST with DD_DOGSTATSD_PORT.
+func (o options) address() string {
+	switch {
+	case o.addr != "":
+		return o.addr
+	case os.Getenv("DD_DOGSTATSD_URL") != "":
+		return ""
+	case o.agentHost == "" && os.Getenv("DD_AGENT_HOST") != "":
+		return ""
+	}
+
+	return net.JoinHostPort(cmp.Or(o.agentHost, "localhost"), cmp.Or(os.Getenv("DD_DOGSTATSD_PORT"), defaultPort))
+}
+
+// NewMetrics creates a DogStatsD client with the provided options.
+// Example:
+//
+//	m, err := metrics.NewMetrics(metrics.WithService("orders"), metrics.WithTags("team:checkout"))
+//	m.Count("orders.created", 1, "payment_method:card")
+func NewMetrics(opts ...MetricsOption) (Metrics, error) {
+	o := newOptions(opts)
+
+	client, err := statsd.New(o.address(), statsd.WithTags(o.globalTags()))
+	if err != nil {
+		return nil, err
+	}
+
+	return statsdMetrics{client: client}, nil
+}
+
+// statsdMetrics sends the metrics with a DogStatsD client, errors are dropped like DogStatsD drops metrics it can't send.
+type statsdMetrics struct {
+	client statsd.ClientInterface
+}
+
+func (m statsdMetrics) Count(name string, value int64, tags ...string) {
+	_ = m.client.Count(name, value, tags, 1)
+}
+
+func (m statsdMetrics) Gauge(name string, value float64, tags ...string) {
+	_ = m.client.Gauge(name, value, tags, 1)
+}
+
+func (m statsdMetrics) Histogram(name string, value float64, tags ...string) {
+	_ = m.client.Histogram(name, value, tags, 1)
+}
+
+func (m statsdMetrics) Distribution(name string, value float64, tags ...string) {
+	_ = m.client.Distribution(name, value, tags, 1)
+}
+
+func (m statsdMetrics) Timing(name string, value time.Duration, tags ...string) {
+	_ = m.client.Timing(name, value, tags, 1)
+}
+
+// Close flushes the pending metrics and closes the client.
+func (m statsdMetrics) Close() error {
+	return m.client.Close()
+}
+
+// NoopMetrics drops every metric, it is used when metrics are disabled.
+type NoopMetrics struct{}
+
+func NewNoopMetrics() Metrics {
+	return NoopMetrics{}
+}
+
+func (NoopMetrics) Count(string, int64, ...string)          {}
+func (NoopMetrics) Gauge(string, float64, ...string)        {}
+func (NoopMetrics) Histogram(string, float64, ...string)    {}
+func (NoopMetrics) Distribution(string, float64, ...string) {}
+func (NoopMetrics) Timing(string, time.Duration, ...string) {}
+func (NoopMetrics) Close() error                            { return nil }
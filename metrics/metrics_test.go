@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMetrics(t *testing.T) {
+	socketDir, err := os.MkdirTemp("", "dsd")
+	if err != nil {
+		t.Fatalf("Unexpected error while creating the socket dir: %v", err)
+	}
+	defer os.RemoveAll(socketDir)
+
+	tests := []struct {
+		name   string
+		listen func() (*Listener, error)
+	}{
+		{
+			name:   "udp",
+			listen: ListenUDP,
+		},
+		{
+			name: "unix domain socket",
+			listen: func() (*Listener, error) {
+				return ListenUnixgram(filepath.Join(socketDir, "dsd.socket"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := tt.listen()
+			if err != nil {
+				t.Fatalf("Unexpected error while listening: %v", err)
+			}
+			defer listener.Close()
+
+			m, err := NewMetrics(
+				WithAddr(listener.Addr()),
+				WithService("orders"),
+				WithEnv("test"),
+				WithVersion("1.2.3"),
+				WithTags("team:checkout"),
+			)
+			if err != nil {
+				t.Fatalf("Unexpected error while creating the metrics: %v", err)
+			}
+
+			m.Count("orders.created", 2, "payment_method:card")
+			m.Gauge("orders.pending", 5, "payment_method:card")
+			m.Histogram("orders.items", 3, "payment_method:card")
+			m.Distribution("orders.amount", 12.5, "payment_method:card")
+			m.Timing("orders.checkout", 1500*time.Millisecond, "payment_method:card")
+			if err := m.Close(); err != nil {
+				t.Fatalf("Unexpected error while closing the metrics: %v", err)
+			}
+
+			expected := map[string]string{
+				"orders.created:":  "orders.created:2|c|",
+				"orders.pending:":  "orders.pending:5|g|",
+				"orders.items:":    "orders.items:3|h|",
+				"orders.amount:":   "orders.amount:12.5|d|",
+				"orders.checkout:": "orders.checkout:1500.000000|ms|",
+			}
+			lines := map[string]string{}
+			for len(lines) < len(expected) {
+				line, err := listener.Next(2 * time.Second)
+				if err != nil {
+					t.Fatalf("Unexpected error while reading the metrics, got %v: %v", lines, err)
+				}
+				name, _, _ := strings.Cut(line, ":")
+				lines[name+":"] = line
+			}
+
+			for prefix, expectedStart := range expected {
+				line := lines[prefix]
+				if !strings.HasPrefix(line, expectedStart) {
+					t.Errorf("Metric incorrect, expected '%s...' got '%s'", expectedStart, line)
+				}
+				for _, tag := range []string{"service:orders", "env:test", "version:1.2.3", "team:checkout", "payment_method:card"} {
+					if !strings.Contains(line, tag) {
+						t.Errorf("Metric tags incorrect, expected '%s' in '%s'", tag, line)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewMetricsUnifiedServiceTags(t *testing.T) {
+	t.Setenv("DD_SERVICE", "orders-env")
+	t.Setenv("DD_ENV", "")
+	t.Setenv("DD_VERSION", "")
+
+	listener, err := ListenUDP()
+	if err != nil {
+		t.Fatalf("Unexpected error while listening: %v", err)
+	}
+	defer listener.Close()
+
+	m, err := NewMetrics(WithAddr(listener.Addr()), WithService("orders"), WithEnv("test"))
+	if err != nil {
+		t.Fatalf("Unexpected error while creating the metrics: %v", err)
+	}
+	m.Count("orders.created", 1)
+	if err := m.Close(); err != nil {
+		t.Fatalf("Unexpected error while closing the metrics: %v", err)
+	}
+
+	line, err := listener.NextWithPrefix("orders.created:", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error while reading the metrics: %v", err)
+	}
+
+	// DD_SERVICE is added by the DogStatsD client, the service of the option would be a second service tag
+	_, tags, _ := strings.Cut(line, "|#")
+	var serviceTags []string
+	for _, tag := range strings.Split(tags, ",") {
+		if strings.HasPrefix(tag, "service:") {
+			serviceTags = append(serviceTags, tag)
+		}
+	}
+	if len(serviceTags) != 1 || serviceTags[0] != "service:orders-env" {
+		t.Errorf("Service tags incorrect, expected '[service:orders-env]' got '%v'", serviceTags)
+	}
+	if !strings.Contains(tags, "env:test") {
+		t.Errorf("Metric tags incorrect, expected 'env:test' in '%s'", tags)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		opts     []MetricsOption
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: "localhost:8125",
+		},
+		{
+			name:     "address option",
+			env:      map[string]string{"DD_DOGSTATSD_URL": "udp://statsd:8125"},
+			opts:     []MetricsOption{WithAddr("unix:///var/run/datadog/dsd.socket")},
+			expected: "unix:///var/run/datadog/dsd.socket",
+		},
+		{
+			name:     "url resolved by the client",
+			env:      map[string]string{"DD_DOGSTATSD_URL": "udp://statsd:8125"},
+			opts:     []MetricsOption{WithAgentHost("agent")},
+			expected: "",
+		},
+		{
+			name:     "agent host option",
+			env:      map[string]string{"DD_AGENT_HOST": "other-agent", "DD_DOGSTATSD_PORT": "8127"},
+			opts:     []MetricsOption{WithAgentHost("agent")},
+			expected: "agent:8127",
+		},
+		{
+			name:     "agent host resolved by the client",
+			env:      map[string]string{"DD_AGENT_HOST": "other-agent"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DD_DOGSTATSD_URL", "DD_AGENT_HOST", "DD_DOGSTATSD_PORT"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			if address := newOptions(tt.opts).address(); address != tt.expected {
+				t.Errorf("Address incorrect, expected '%s' got '%s'", tt.expected, address)
+			}
+		})
+	}
+}
+
+func TestNoopMetrics(t *testing.T) {
+	m := NewNoopMetrics()
+	m.Count("orders.created", 1)
+	m.Timing("orders.checkout", time.Second)
+
+	if err := m.Close(); err != nil {
+		t.Errorf("Unexpected error while closing the metrics: %v", err)
+	}
+}
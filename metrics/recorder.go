@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+type MetricType string
+
+const (
+	TypeCount        MetricType = "count"
+	TypeGauge        MetricType = "gauge"
+	TypeHistogram    MetricType = "histogram"
+	TypeDistribution MetricType = "distribution"
+	TypeTiming       MetricType = "timing"
+)
+
+// Metric is a metric kept by the Recorder, the value of a timing is in milliseconds like on the wire.
+type Metric struct {
+	Type  MetricType
+	Name  string
+	Value float64
+	Tags  []string
+}
+
+// Recorder keeps the metrics in memory, to test the metrics of code without DogStatsD.
+// The tags of a metric start with the service, env, version and tags of the options, like the DogStatsD client.
+type Recorder struct {
+	mu      sync.Mutex
+	tags    []string
+	metrics []Metric
+}
+
+// NewRecorder creates a Recorder with the provided options, the address options are ignored.
+// Example:
+//
+//	recorder := metrics.NewRecorder(metrics.WithService("orders"))
+//	apm := apm.NewApm(apm.WithMetricsClient(recorder))
+func NewRecorder(opts ...MetricsOption) *Recorder {
+	return &Recorder{tags: newOptions(opts).globalTags()}
+}
+
+func (r *Recorder) record(metricType MetricType, name string, value float64, tags []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, Metric{
+		Type:  metricType,
+		Name:  name,
+		Value: value,
+		Tags:  append(slices.Clone(r.tags), tags...),
+	})
+}
+
+func (r *Recorder) Count(name string, value int64, tags ...string) {
+	r.record(TypeCount, name, float64(value), tags)
+}
+
+func (r *Recorder) Gauge(name string, value float64, tags ...string) {
+	r.record(TypeGauge, name, value, tags)
+}
+
+func (r *Recorder) Histogram(name string, value float64, tags ...string) {
+	r.record(TypeHistogram, name, value, tags)
+}
+
+func (r *Recorder) Distribution(name string, value float64, tags ...string) {
+	r.record(TypeDistribution, name, value, tags)
+}
+
+func (r *Recorder) Timing(name string, value time.Duration, tags ...string) {
+	r.record(TypeTiming, name, float64(value)/float64(time.Millisecond), tags)
+}
+
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Metrics returns the recorded metrics, in the order they were recorded.
+func (r *Recorder) Metrics() []Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.metrics)
+}
+
+// Last returns the last recorded metric with the name.
+func (r *Recorder) Last(name string) (Metric, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.metrics) - 1; i >= 0; i-- {
+		if r.metrics[i].Name == name {
+			return r.metrics[i], true
+		}
+	}
+	return Metric{}, false
+}
+
+// Reset removes the recorded metrics.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = nil
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder(WithService("orders"), WithTags("team:checkout"))
+
+	recorder.Count("orders.created", 2, "payment_method:card")
+	recorder.Gauge("orders.pending", 5)
+	recorder.Histogram("orders.items", 3)
+	recorder.Distribution("orders.amount", 12.5)
+	recorder.Timing("orders.checkout", 1500*time.Millisecond)
+	recorder.Count("orders.created", 1, "payment_method:invoice")
+
+	expected := []Metric{
+		{Type: TypeCount, Name: "orders.created", Value: 2, Tags: []string{"service:orders", "team:checkout", "payment_method:card"}},
+		{Type: TypeGauge, Name: "orders.pending", Value: 5, Tags: []string{"service:orders", "team:checkout"}},
+		{Type: TypeHistogram, Name: "orders.items", Value: 3, Tags: []string{"service:orders", "team:checkout"}},
+		{Type: TypeDistribution, Name: "orders.amount", Value: 12.5, Tags: []string{"service:orders", "team:checkout"}},
+		{Type: TypeTiming, Name: "orders.checkout", Value: 1500, Tags: []string{"service:orders", "team:checkout"}},
+		{Type: TypeCount, Name: "orders.created", Value: 1, Tags: []string{"service:orders", "team:checkout", "payment_method:invoice"}},
+	}
+	if !reflect.DeepEqual(recorder.Metrics(), expected) {
+		t.Errorf("Metrics incorrect, expected '%v' got '%v'", expected, recorder.Metrics())
+	}
+
+	last, ok := recorder.Last("orders.created")
+	if !ok || last.Value != 1 {
+		t.Errorf("Last metric incorrect, expected value '1' got '%v' (found %v)", last.Value, ok)
+	}
+	if _, ok := recorder.Last("orders.cancelled"); ok {
+		t.Error("expected no metric for orders.cancelled")
+	}
+
+	recorder.Reset()
+	if len(recorder.Metrics()) != 0 {
+		t.Errorf("expected no metrics after Reset, got %d", len(recorder.Metrics()))
+	}
+}